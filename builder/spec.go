@@ -7,7 +7,7 @@ import (
 	"sort"
 	"strings"
 
-	"isolator-builder/internal/hk"
+	"isolator-hk"
 )
 
 // BuildSpec is a fully-resolved build request: the .hk file's contents,
@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"isolator-daemon/internal/hk"
+	"isolator-hk"
 )
 
 // DaemonConfig is the daemon's own schedule, parsed from daemon.hk —
@@ -19,6 +21,8 @@ type DaemonConfig struct {
 	SnapshotBeforeUpdate bool
 	SocketPath           string
 	LogPath              string
+	LogMaxSize           int64 // bytes; 0 disables rotation
+	LogMaxFiles          int   // rotated, gzipped generations to keep
 }
 
 func DefaultDaemonConfig() DaemonConfig {
@@ -29,6 +33,8 @@ func DefaultDaemonConfig() DaemonConfig {
 		SnapshotBeforeUpdate: true,
 		SocketPath:           "/run/isolator-daemon.sock",
 		LogPath:              "",
+		LogMaxSize:           10 << 20,
+		LogMaxFiles:          5,
 	}
 }
 
@@ -82,6 +88,25 @@ func LoadDaemonConfig(path string) (DaemonConfig, error) {
 			cfg.LogPath = s
 		}
 	}
+	if v, ok := logSec.Get("max_size"); ok {
+		s, err := v.AsString()
+		if err == nil {
+			var n int64
+			if n, err = ParseSize(s); err == nil {
+				cfg.LogMaxSize = n
+			}
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("[log] -> max_size: %w", err)
+		}
+	}
+	if v, ok := logSec.Get("max_files"); ok {
+		n, err := v.AsNumber()
+		if err != nil || n < 0 || n != float64(int(n)) {
+			return cfg, fmt.Errorf("[log] -> max_files should be a non-negative whole number")
+		}
+		cfg.LogMaxFiles = int(n)
+	}
 
 	return cfg, nil
 }
@@ -97,3 +122,30 @@ func getDuration(m *hk.HkMap, key string) (time.Duration, error) {
 	}
 	return time.ParseDuration(s)
 }
+
+// ParseSize reads a byte count like "10485760", "512K", "10M" or "1G"
+// (binary multiples; a trailing "B"/"iB" is accepted too). "0" turns
+// rotation off.
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "B"), "I")
+	mult := int64(1)
+	if n := len(t); n > 0 {
+		switch t[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult != 1 {
+			t = t[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 10M, 512K or a byte count)", s)
+	}
+	return n * mult, nil
+}
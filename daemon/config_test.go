@@ -98,3 +98,26 @@ func TestTaskArgs(t *testing.T) {
 		t.Errorf("expected --dry-run appended, got %v", got)
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":     0,
+		"4096":  4096,
+		"512K":  512 << 10,
+		"10M":   10 << 20,
+		"10MiB": 10 << 20,
+		"1g":    1 << 30,
+		" 2MB ": 2 << 20,
+	}
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ten", "-1M", "10T"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("expected ParseSize(%q) to fail", in)
+		}
+	}
+}
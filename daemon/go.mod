@@ -1,3 +1,7 @@
 module isolator-daemon
 
 go 1.22
+
+require isolator-hk v0.0.0
+
+replace isolator-hk => ../hk
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sync"
 )
 
 // Logger wraps the standard log.Logger, writing to a file if LogPath is
@@ -13,23 +15,26 @@ import (
 // `daemon run` in the foreground during development/debugging).
 type Logger struct {
 	*log.Logger
-	file *os.File
+	file *rotatingFile
 }
 
-func NewLogger(path string) (*Logger, error) {
+// NewLogger opens path for appending. With maxSize > 0 the file is rotated
+// once it would grow past maxSize bytes, keeping at most maxFiles gzipped
+// older generations next to it (path.1.gz is the newest).
+func NewLogger(path string, maxSize int64, maxFiles int) (*Logger, error) {
 	var w io.Writer = os.Stdout
-	var f *os.File
+	var rf *rotatingFile
 	if path != "" {
 		var err error
-		f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		rf, err = openRotatingFile(path, maxSize, maxFiles)
 		if err != nil {
-			return nil, fmt.Errorf("opening log file %s: %w", path, err)
+			return nil, err
 		}
-		w = f
+		w = rf
 	}
 	return &Logger{
 		Logger: log.New(w, "isolator-daemon: ", log.LstdFlags),
-		file:   f,
+		file:   rf,
 	}, nil
 }
 
@@ -39,6 +44,172 @@ func (l *Logger) Close() {
 	}
 }
 
+// rotatingFile is the io.Writer behind a file-backed Logger. Every write
+// and every rotation happens under mu, so a log line is never split
+// across the old and new file, nor written to a file that's mid-rename.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening log file %s: %w", rf.path, err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening log file %s: %w", rf.path, err)
+	}
+	rf.f, rf.size = f, st.Size()
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			// Keep logging into whatever file we still have rather than
+			// losing the line; the next write will try rotating again.
+			fmt.Fprintf(os.Stderr, "isolator-daemon: log rotation failed: %v\n", err)
+			if rf.f == nil {
+				if err := rf.open(); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N.gz → path.N+1.gz (dropping anything past maxFiles),
+// compresses the current file into path.1.gz and starts a fresh one.
+// Called with mu held.
+func (rf *rotatingFile) rotate() error {
+	// A path.1 left by a compression that failed last time is the newest
+	// rotated generation: fold it in first, or the rename below would
+	// overwrite it. Until that works the current file just keeps growing.
+	plain := rf.path + ".1"
+	if rf.maxFiles > 0 {
+		if _, err := os.Stat(plain); err == nil {
+			if err := rf.shift(); err != nil {
+				return err
+			}
+			if err := gzipFile(plain, rf.generation(1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	rf.f = nil
+
+	if rf.maxFiles <= 0 {
+		if err := os.Remove(rf.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return rf.open()
+	}
+
+	if err := rf.shift(); err != nil {
+		return err
+	}
+	// Rename first so new lines can go to a fresh file right away, then
+	// compress the renamed copy.
+	if err := os.Rename(rf.path, plain); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	return gzipFile(plain, rf.generation(1))
+}
+
+// shift moves each path.N.gz up one, freeing path.1.gz and dropping the
+// one that would go past maxFiles.
+func (rf *rotatingFile) shift() error {
+	os.Remove(rf.generation(rf.maxFiles))
+	for i := rf.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rf.generation(i), rf.generation(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rf *rotatingFile) generation(i int) string {
+	return fmt.Sprintf("%s.%d.gz", rf.path, i)
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+// gzipFile compresses src into dst (via a temp file renamed into place, so
+// a crash never leaves a truncated .gz behind) and removes src.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(src)
+}
+
 func stdoutStderr() (io.Writer, io.Writer) {
 	return os.Stdout, os.Stderr
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileKeepsMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	rf, err := openRotatingFile(path, 64, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	defer rf.Close()
+
+	line := strings.Repeat("x", 40) + "\n"
+	for i := 0; i < 5; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	for _, name := range []string{path + ".1.gz", path + ".2.gz"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s is not valid gzip: %v", name, err)
+		}
+		data, _ := io.ReadAll(zr)
+		f.Close()
+		if string(data) != line {
+			t.Errorf("%s: expected one log line, got %q", name, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3.gz"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files to be kept")
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected the uncompressed rotated file to be removed")
+	}
+}
+
+func TestRotatingFileFoldsInLeftoverPlainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	// What a rotation whose compression failed leaves behind.
+	if err := os.WriteFile(path+".1", []byte("leftover\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := openRotatingFile(path, 64, 3)
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	defer rf.Close()
+
+	line := strings.Repeat("x", 40) + "\n"
+	for i := 0; i < 2; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	for name, want := range map[string]string{path + ".1.gz": line, path + ".2.gz": "leftover\n"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s is not valid gzip: %v", name, err)
+		}
+		data, _ := io.ReadAll(zr)
+		f.Close()
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected the leftover uncompressed file to be folded in")
+	}
+}
@@ -44,6 +44,8 @@ func checkIsolator() error {
 
 func runRunCmd(args []string) {
 	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	logMaxSize := fs.String("log-max-size", "", "rotate the log file once it reaches this size, e.g. 10M (0 disables; default from daemon.hk, else 10M)")
+	logMaxFiles := fs.Int("log-max-files", -1, "number of rotated, gzipped log files to keep (default from daemon.hk, else 5)")
 	fs.Parse(args)
 
 	if err := checkIsolator(); err != nil {
@@ -61,7 +63,19 @@ func runRunCmd(args []string) {
 		os.Exit(1)
 	}
 
-	logger, err := NewLogger(cfg.LogPath)
+	if *logMaxSize != "" {
+		n, err := ParseSize(*logMaxSize)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "--log-max-size: "+err.Error())
+			os.Exit(1)
+		}
+		cfg.LogMaxSize = n
+	}
+	if *logMaxFiles >= 0 {
+		cfg.LogMaxFiles = *logMaxFiles
+	}
+
+	logger, err := NewLogger(cfg.LogPath, cfg.LogMaxSize, cfg.LogMaxFiles)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -160,7 +174,8 @@ own module, requires isolator on PATH — it has no container-management
 logic of its own, it's a clock that calls the real isolator commands.
 
 Usage:
-  isolator-daemon run [daemon.hk]        run in the foreground (systemd
+  isolator-daemon run [--log-max-size SIZE] [--log-max-files N] [daemon.hk]
+                                          run in the foreground (systemd
                                           manages backgrounding — see
                                           the example unit below)
   isolator-daemon status [--socket PATH]
@@ -180,7 +195,9 @@ daemon.hk (all keys optional — every one has a sane default):
   -> path => /run/isolator-daemon.sock
 
   [log]
-  -> path => /var/log/isolator-daemon.log
+  -> path      => /var/log/isolator-daemon.log
+  -> max_size  => 10M     (rotate past this; 0 disables rotation)
+  -> max_files => 5       (keep path.1.gz … path.5.gz)
 
 Example systemd unit (pairs with Builder's generated
 isolator-first-boot.service — both assume Isolator itself is already set
@@ -0,0 +1,3 @@
+module isolator-hk
+
+go 1.22
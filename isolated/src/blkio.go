@@ -0,0 +1,124 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// deviceMajorMinor returns the major:minor numbers of the block device at
+// path, the identity cgroup I/O limits are keyed by. Anything that isn't
+// a block device (a partition's mount point, a character device, a typo)
+// is an error, since the kernel would reject or silently ignore the limit.
+func deviceMajorMinor(path string) (uint32, uint32, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", path, err)
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+		return 0, 0, fmt.Errorf("%s is not a block device (expected e.g. /dev/nvme0n1)", path)
+	}
+	major, minor := splitRdev(uint64(st.Rdev))
+	return major, minor, nil
+}
+
+// splitRdev decodes a device number in the kernel's new_encode_dev()
+// layout, as glibc's major()/minor() do.
+func splitRdev(rdev uint64) (major, minor uint32) {
+	major = uint32((rdev>>8)&0xfff) | uint32((rdev>>32)&^0xfff)
+	minor = uint32(rdev&0xff) | uint32((rdev>>12)&^0xff)
+	return major, minor
+}
+
+// parseIOPSLimit splits a --device-read-iops/--device-write-iops value,
+// "/dev/nvme0n1:1000", into the device and its operations-per-second cap.
+func parseIOPSLimit(spec string) (string, uint64, error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("%q should be DEVICE:IOPS, e.g. /dev/nvme0n1:1000", spec)
+	}
+	path, rate := spec[:i], spec[i+1:]
+	n, err := strconv.ParseUint(rate, 10, 64)
+	if err != nil || n == 0 {
+		return "", 0, fmt.Errorf("%q: IOPS should be a positive whole number", spec)
+	}
+	if _, _, err := deviceMajorMinor(path); err != nil {
+		return "", 0, err
+	}
+	return path, n, nil
+}
+
+// buildIOPSArgs passes the limits to podman, which writes them to io.max
+// (riops=/wiops=) on cgroup v2 or blkio.throttle.*_iops_device on v1.
+func buildIOPSArgs(read, write []string) []string {
+	var args []string
+	for _, l := range []struct {
+		flag  string
+		specs []string
+	}{{"--device-read-iops", read}, {"--device-write-iops", write}} {
+		for _, spec := range l.specs {
+			path, n, err := parseIOPSLimit(spec)
+			if err != nil {
+				continue
+			}
+			args = append(args, l.flag, path+":"+strconv.FormatUint(n, 10))
+		}
+	}
+	return args
+}
+
+// controllerDelegationWarning flags rootless hosts where the container
+// won't get a delegated scope, or where systemd hasn't delegated
+// controller to the user's cgroup; flags names the options that need it.
+// Either makes podman refuse to create the container with those limits.
+// systemd delegates cpu, io and memory to users by default (though a site
+// can turn that off), but not cpuset.
+func controllerDelegationWarning(flags, controller string) []string {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if why := rootlessScopeProblem(); why != "" {
+		return []string{flags + ": " + why}
+	}
+	uid := strconv.Itoa(os.Getuid())
+	path := "/sys/fs/cgroup/user.slice/user-" + uid + ".slice/user@" + uid + ".service/cgroup.controllers"
+	data, err := os.ReadFile(path)
+	if err != nil || stringInSlice(controller, strings.Fields(string(data))) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: the %s cgroup controller isn't delegated to your user, so rootless podman can't apply these limits — add 'Delegate=%s' for user@.service (see systemd.resource-control(5))", flags, controller, controller)}
+}
+
+// rootlessScopeProblem explains why rootless podman can't put a new
+// container in a delegated cgroup of its own, or returns "". With the
+// systemd cgroup manager podman asks the user's systemd instance (over
+// D-Bus) for a transient libpod-<id>.scope under user@UID.service, which
+// is what makes limits writable without root, and systemd removes the
+// scope when the container stops. With cgroupfs, or with no user session
+// to ask, the container just stays in the caller's cgroup. On cgroup v1
+// nothing is delegated to users at all, and podman drops every limit.
+func rootlessScopeProblem() string {
+	if !hostCgroupV2() {
+		return "this host uses cgroup v1, where rootless podman ignores resource limits altogether — run the install with sudo, or boot with systemd.unified_cgroup_hierarchy=1"
+	}
+	out, err := exec.Command(podmanBin, "info", "--format", "{{.Host.CgroupManager}}").Output()
+	if err != nil {
+		return ""
+	}
+	if strings.TrimSpace(string(out)) != "systemd" {
+		return "rootless podman is set to the cgroupfs cgroup manager, so it can't create a delegated systemd scope to apply limits in — set cgroup_manager = \"systemd\" in containers.conf(5)"
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = "/run/user/" + strconv.Itoa(os.Getuid())
+	}
+	if _, err := os.Stat(filepath.Join(runtimeDir, "systemd/private")); err != nil {
+		return "there's no systemd user session for podman to create the container's scope in (e.g. an ssh or su login without one) — log in normally, or run 'loginctl enable-linger'"
+	}
+	return ""
+}
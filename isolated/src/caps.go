@@ -0,0 +1,150 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// capabilityNumbers lists every capability defined in linux/capability.h,
+// keyed by its canonical CAP_* name. The numbers aren't passed to podman
+// (it takes names), but keeping them makes this the same table the kernel
+// header is — easy to diff when new capabilities appear.
+var capabilityNumbers = map[string]uintptr{
+	"CAP_CHOWN":              0,
+	"CAP_DAC_OVERRIDE":       1,
+	"CAP_DAC_READ_SEARCH":    2,
+	"CAP_FOWNER":             3,
+	"CAP_FSETID":             4,
+	"CAP_KILL":               5,
+	"CAP_SETGID":             6,
+	"CAP_SETUID":             7,
+	"CAP_SETPCAP":            8,
+	"CAP_LINUX_IMMUTABLE":    9,
+	"CAP_NET_BIND_SERVICE":   10,
+	"CAP_NET_BROADCAST":      11,
+	"CAP_NET_ADMIN":          12,
+	"CAP_NET_RAW":            13,
+	"CAP_IPC_LOCK":           14,
+	"CAP_IPC_OWNER":          15,
+	"CAP_SYS_MODULE":         16,
+	"CAP_SYS_RAWIO":          17,
+	"CAP_SYS_CHROOT":         18,
+	"CAP_SYS_PTRACE":         19,
+	"CAP_SYS_PACCT":          20,
+	"CAP_SYS_ADMIN":          21,
+	"CAP_SYS_BOOT":           22,
+	"CAP_SYS_NICE":           23,
+	"CAP_SYS_RESOURCE":       24,
+	"CAP_SYS_TIME":           25,
+	"CAP_SYS_TTY_CONFIG":     26,
+	"CAP_MKNOD":              27,
+	"CAP_LEASE":              28,
+	"CAP_AUDIT_WRITE":        29,
+	"CAP_AUDIT_CONTROL":      30,
+	"CAP_SETFCAP":            31,
+	"CAP_MAC_OVERRIDE":       32,
+	"CAP_MAC_ADMIN":          33,
+	"CAP_SYSLOG":             34,
+	"CAP_WAKE_ALARM":         35,
+	"CAP_BLOCK_SUSPEND":      36,
+	"CAP_AUDIT_READ":         37,
+	"CAP_PERFMON":            38,
+	"CAP_BPF":                39,
+	"CAP_CHECKPOINT_RESTORE": 40,
+}
+
+// NormalizeCapability accepts a capability in any of the spellings people
+// actually type ("net_raw", "NET_RAW", "CAP_NET_RAW") and returns its
+// canonical CAP_* name, or an error for anything the kernel doesn't define
+// — so a typo fails the install instead of reaching podman as a silently
+// ignored or rejected flag after the image has already been pulled.
+func NormalizeCapability(name string) (string, error) {
+	canon := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(canon, "CAP_") {
+		canon = "CAP_" + canon
+	}
+	if _, ok := capabilityNumbers[canon]; !ok {
+		return "", fmt.Errorf("unknown capability %q (expected a name from capabilities(7), e.g. NET_RAW)", name)
+	}
+	return canon, nil
+}
+
+// pingGroupRange is the ping_group_range that lets every group the
+// container can see open ICMP echo sockets. The kernel refuses the write
+// (EINVAL, so the container never starts) unless both ends are mapped in
+// the writer's user namespace. Rootful containers see every gid, so the
+// whole range works; a rootless keep-id container only sees the user's
+// own gid and the subgid range — see keepIDMap — and without a range the
+// user's gid is the only one that's safe to name.
+func pingGroupRange(rootful bool, gid int, sub *idRange) string {
+	switch {
+	case rootful:
+		return "net.ipv4.ping_group_range=0 2147483647"
+	case sub != nil:
+		return fmt.Sprintf("net.ipv4.ping_group_range=0 %d", keepIDMap{gid, *sub}.maxMapped())
+	}
+	return fmt.Sprintf("net.ipv4.ping_group_range=%d %d", gid, gid)
+}
+
+// hostPingGroupRange is pingGroupRange for the user running isolator.
+func hostPingGroupRange() string {
+	if os.Geteuid() == 0 {
+		return pingGroupRange(true, 0, nil)
+	}
+	gid := os.Getgid()
+	if u, err := user.Current(); err == nil {
+		if sub, err := subordinateRange("/etc/subgid", u.Username, os.Getuid()); err == nil {
+			return pingGroupRange(false, gid, &sub)
+		}
+	}
+	return pingGroupRange(false, gid, nil)
+}
+
+// buildPingArgs makes ping work for the user's own processes when NET_RAW
+// is granted. Podman's added capabilities only reach a root process's
+// effective set, and under --userns=keep-id packages run as the user: ping
+// gets CAP_NET_RAW there only from a setuid bit or file capability, which
+// many images' ping (busybox's, say) doesn't have. iputils and busybox
+// fall back to an unprivileged ICMP socket, which the kernel allows only
+// to groups in net.ipv4.ping_group_range — podman's default is "0 0",
+// root's alone — so NET_RAW also opens that to every group the container
+// has, in its own network namespace. An explicit --sysctl for it wins. No
+// device rule is involved: sockets aren't device nodes.
+func buildPingArgs(add, sysctls []string) []string {
+	netRaw := false
+	for _, c := range add {
+		if canon, err := NormalizeCapability(c); err == nil && canon == "CAP_NET_RAW" {
+			netRaw = true
+		}
+	}
+	if !netRaw {
+		return nil
+	}
+	for _, s := range sysctls {
+		if strings.HasPrefix(s, "net.ipv4.ping_group_range=") {
+			return nil
+		}
+	}
+	return []string{"--sysctl", hostPingGroupRange()}
+}
+
+// buildCapabilityArgs turns the capability options into podman flags.
+// --cap-drop=all clears the permitted, effective, inheritable, bounding and
+// ambient sets alike; the --cap-add entries that follow are then the only
+// capabilities the container gets back. Names are assumed validated.
+func buildCapabilityArgs(dropAll bool, add []string) []string {
+	var args []string
+	if dropAll {
+		args = append(args, "--cap-drop=all")
+	}
+	for _, c := range add {
+		canon, err := NormalizeCapability(c)
+		if err != nil {
+			continue
+		}
+		args = append(args, "--cap-add="+canon)
+	}
+	return args
+}
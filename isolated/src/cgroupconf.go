@@ -0,0 +1,55 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cgroupConfKeyRe is a cgroup v2 interface file: controller.file, as in
+// cpu.idle, memory.high or io.weight. No "/" can get through, so a key
+// can't reach outside the container's own cgroup directory.
+var cgroupConfKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]*\.[a-z0-9_.]+$`)
+
+// validateCgroupConf checks --cgroup-conf entries, KEY=VALUE with the
+// value written as is. Keys Isolator sets itself from another option are
+// refused, rather than letting the two fight over the same file; that
+// includes cpu.weight under --cpu-shares, which podman converts into it on
+// cgroup v2.
+func validateCgroupConf(entries []string, cpuShares, cpuWeight int) error {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		key, _, ok := strings.Cut(e, "=")
+		if !ok || !cgroupConfKeyRe.MatchString(key) || strings.Contains(key, "..") {
+			return fmt.Errorf("--cgroup-conf %q should be KEY=VALUE with KEY a cgroup file like cpu.idle or memory.high", e)
+		}
+		if seen[key] {
+			return fmt.Errorf("--cgroup-conf: %s is set twice", key)
+		}
+		seen[key] = true
+		if key == "cpu.weight" && cpuWeight != 0 {
+			return fmt.Errorf("--cgroup-conf cpu.weight and --cpu-weight set the same file; use one")
+		}
+		if key == "cpu.weight" && cpuShares != 0 {
+			return fmt.Errorf("--cgroup-conf cpu.weight and --cpu-shares set the same file (podman converts shares to cpu.weight); use one")
+		}
+	}
+	return nil
+}
+
+// checkCgroupConfHost fails on cgroup v1, where podman refuses
+// --cgroup-conf outright.
+func checkCgroupConfHost() error {
+	if !hostCgroupV2() {
+		return fmt.Errorf("--cgroup-conf needs cgroup v2, and this host uses v1 (see the --cpu-shares and --device-*-iops options for what works on both)")
+	}
+	return nil
+}
+
+func buildCgroupConfArgs(entries []string) []string {
+	var args []string
+	for _, e := range entries {
+		args = append(args, "--cgroup-conf="+e)
+	}
+	return args
+}
@@ -0,0 +1,230 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Images need no help here: podman shifts a layer's ownership into the
+// user namespace as it extracts it. A --rootfs tree is used as it is,
+// though, and one built as root (sudo debootstrap) is owned by host uid
+// 0, which a rootless container sees as nobody:nogroup. `isolator chown`
+// rewrites such a tree's owners to the host ids the container's
+// --userns=keep-id mapping shows as the original ones. Files owned by
+// host root can only be chowned by root, so it runs under sudo, for the
+// user who invoked sudo.
+
+// idRange is one /etc/subuid or /etc/subgid entry: Count ids from Start.
+type idRange struct {
+	Start, Count int
+}
+
+// subordinateRange finds the first range file gives name (or its
+// numeric id, which the file may use instead).
+func subordinateRange(file, name string, id int) (idRange, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return idRange{}, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Split(strings.TrimSpace(sc.Text()), ":")
+		if len(fields) != 3 || (fields[0] != name && fields[0] != strconv.Itoa(id)) {
+			continue
+		}
+		start, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 == nil && err2 == nil && count > 0 {
+			return idRange{start, count}, nil
+		}
+	}
+	return idRange{}, fmt.Errorf("%s has no range for %s", file, name)
+}
+
+// keepIDMap is podman's --userns=keep-id for one kind of id: container
+// ids below the user's own come from the subordinate range in order, the
+// user's own id maps to itself, and the ids above it continue the range.
+type keepIDMap struct {
+	own int
+	sub idRange
+}
+
+// host returns the host id container id c is stored as, and false if
+// the subordinate range is too small to map it at all.
+func (m keepIDMap) host(c int) (int, bool) {
+	switch {
+	case c == m.own:
+		return c, true
+	case c < m.own && c < m.sub.Count:
+		return m.sub.Start + c, true
+	case c > m.own && c-1 < m.sub.Count:
+		return m.sub.Start + c - 1, true
+	}
+	return 0, false
+}
+
+// maxMapped is the highest container id the map covers: the end of the
+// range when the user's own id lies within it (every id from 0 up is then
+// mapped), the user's own id otherwise.
+func (m keepIDMap) maxMapped() int {
+	if m.own <= m.sub.Count {
+		return m.sub.Count
+	}
+	return m.own
+}
+
+// mapped reports whether host id h already is one the container can see
+// — the user's own or one of the subordinate range — which is what makes
+// a second run of `isolator chown` skip everything.
+func (m keepIDMap) mapped(h int) bool {
+	return h == m.own || (h >= m.sub.Start && h < m.sub.Start+m.sub.Count)
+}
+
+// chownStats counts what HandleChown did (or, with dry-run, would do).
+type chownStats struct {
+	shifted, skipped, unmappable int
+}
+
+// chownTree rewrites every owner under dir through uids and gids. chown
+// clears setuid/setgid bits and file capabilities (ping's cap_net_raw,
+// say), so both are put back afterwards.
+func chownTree(dir string, uids, gids keepIDMap, dryRun bool, progress func(n int)) (chownStats, error) {
+	var st chownStats
+	n := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if n++; progress != nil && n%10000 == 0 {
+			progress(n)
+		}
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		sys, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		uid, gid := int(sys.Uid), int(sys.Gid)
+		newUID, newGID := uid, gid
+		uok, gok := true, true
+		if !uids.mapped(uid) {
+			newUID, uok = uids.host(uid)
+		}
+		if !gids.mapped(gid) {
+			newGID, gok = gids.host(gid)
+		}
+		switch {
+		case !uok || !gok:
+			st.unmappable++
+			return nil
+		case newUID == uid && newGID == gid:
+			st.skipped++
+			return nil
+		}
+		st.shifted++
+		if dryRun {
+			return nil
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return os.Lchown(path, newUID, newGID)
+		}
+		caps := make([]byte, 64)
+		capLen, capErr := syscall.Getxattr(path, "security.capability", caps)
+		if err := os.Lchown(path, newUID, newGID); err != nil {
+			return err
+		}
+		if fi.Mode()&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+			if err := os.Chmod(path, fi.Mode()); err != nil {
+				return err
+			}
+		}
+		if capErr == nil && capLen > 0 {
+			if err := syscall.Setxattr(path, "security.capability", caps[:capLen], 0); err != nil {
+				return fmt.Errorf("%s: restoring file capabilities: %v", path, err)
+			}
+		}
+		return nil
+	})
+	return st, err
+}
+
+// sudoUser is who `sudo isolator chown` is run for.
+func sudoUser() (name string, uid, gid int, err error) {
+	name = os.Getenv("SUDO_USER")
+	uid, err1 := strconv.Atoi(os.Getenv("SUDO_UID"))
+	gid, err2 := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if name == "" || err1 != nil || err2 != nil {
+		return "", 0, 0, fmt.Errorf("isolator chown has to be run with sudo by the user whose containers will use the tree")
+	}
+	return name, uid, gid, nil
+}
+
+// HandleChown shifts a --rootfs tree's ownership for the sudo user's
+// rootless containers.
+func HandleChown(dir string, dryRun bool) {
+	if os.Geteuid() != 0 {
+		PrintError("isolator chown needs root to change files owned by other users — run it with sudo")
+		return
+	}
+	name, uid, gid, err := sudoUser()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if err := validateRootFS(dir); err != nil {
+		PrintError(strings.Replace(err.Error(), "--rootfs ", "", 1))
+		return
+	}
+	subUID, err := subordinateRange("/etc/subuid", name, uid)
+	if err != nil {
+		PrintError(err.Error() + " — rootless podman needs one (see subuid(5))")
+		return
+	}
+	subGID, err := subordinateRange("/etc/subgid", name, gid)
+	if err != nil {
+		PrintError(err.Error() + " — rootless podman needs one (see subgid(5))")
+		return
+	}
+	uids, gids := keepIDMap{uid, subUID}, keepIDMap{gid, subGID}
+
+	PrintStep(fmt.Sprintf("Shifting ownership of %s into %s's user namespace (uids %d-%d, gids %d-%d)...", dir, name, subUID.Start, subUID.Start+subUID.Count-1, subGID.Start, subGID.Start+subGID.Count-1))
+	st, err := chownTree(dir, uids, gids, dryRun, func(n int) {
+		fmt.Println(DimStyle.Render(fmt.Sprintf("  %d files checked", n)))
+	})
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if st.unmappable > 0 {
+		PrintWarn(fmt.Sprintf("%d files have owners beyond %s's subordinate ranges and were left alone — they'll show up as nobody:nogroup", st.unmappable, name))
+	}
+	if dryRun {
+		PrintInfo(fmt.Sprintf("[dry-run] Would shift %d files (%d already in range); no changes made", st.shifted, st.skipped))
+		return
+	}
+	PrintSuccess(fmt.Sprintf("Shifted %d files (%d already in range) — use it with isolator install --rootfs %s as %s", st.shifted, st.skipped, dir, name))
+}
+
+// rootfsOwnerWarning flags a --rootfs tree whose top directory is still
+// owned by host root, which keep-id shows as nobody inside a rootless
+// container: one that hasn't been through `isolator chown`.
+func rootfsOwnerWarning(spec string) string {
+	dir, _ := parseRootFS(spec)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return ""
+	}
+	if sys, ok := fi.Sys().(*syscall.Stat_t); ok && sys.Uid == 0 {
+		return "--rootfs: " + dir + " is owned by root, which a rootless container sees as nobody — shift it first with 'sudo isolator chown " + dir + "'"
+	}
+	return ""
+}
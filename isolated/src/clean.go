@@ -3,11 +3,23 @@ package src
 import (
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 // HandleClean prunes dangling Podman images/build cache and sweeps leftover
-// .tmp files from the config directory (interrupted writes).
-func HandleClean(dryRun bool) {
+// .tmp files from the config directory (interrupted writes). It also
+// reports isolated home directories no installed package owns any more;
+// those hold user data, so they're only deleted when homes is set.
+func HandleClean(dryRun, homes bool) {
+	// Without a readable installed list every home would look orphaned, so
+	// skip the check rather than risk offering to delete them all.
+	var orphans []string
+	if installed, err := LoadInstalled(); err != nil {
+		PrintWarn("Couldn't read installed packages, skipping the orphaned home check: " + err.Error())
+	} else {
+		orphans = orphanedHomes(filepath.Join(os.Getenv("HOME"), homesDir), installed)
+	}
+
 	if dryRun {
 		PrintInfo("[dry-run] Would run:")
 		PrintInfo("  - podman image prune -f")
@@ -24,6 +36,11 @@ func HandleClean(dryRun bool) {
 				PrintInfo("  - remove " + itoa(count) + " leftover .tmp file(s) from " + dir)
 			}
 		}
+		if homes {
+			for _, h := range orphans {
+				PrintInfo("  - remove orphaned isolated home: " + h)
+			}
+		}
 		PrintInfo("[dry-run] No changes made")
 		return
 	}
@@ -54,5 +71,48 @@ func HandleClean(dryRun bool) {
 		}
 	}
 
+	switch {
+	case len(orphans) == 0:
+	case homes:
+		removed := 0
+		for _, h := range orphans {
+			if err := os.RemoveAll(h); err != nil {
+				PrintWarn("Failed to remove " + h + ": " + err.Error())
+				continue
+			}
+			removed++
+		}
+		PrintSuccess("Removed " + itoa(removed) + " orphaned isolated home(s)")
+	default:
+		PrintWarn(itoa(len(orphans)) + " isolated home(s) belong to no installed package (kept — run 'isolator clean --homes' to delete them):")
+		for _, h := range orphans {
+			PrintInfo("  " + h)
+		}
+	}
+
 	PrintSuccess("Clean complete")
 }
+
+// orphanedHomes lists the directories under root (~/.isolator/homes) that
+// no installed isolated package uses — left behind when a remove failed
+// halfway, or when the container was deleted by hand with podman.
+func orphanedHomes(root string, installed []InstalledPackage) []string {
+	owned := map[string]bool{}
+	for _, ip := range installed {
+		if ip.Isolated {
+			owned[ip.Pkg] = true
+		}
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+	var orphans []string
+	for _, e := range entries {
+		if e.IsDir() && !owned[e.Name()] {
+			orphans = append(orphans, filepath.Join(root, e.Name()))
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
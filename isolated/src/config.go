@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 // configSchema declares every key Isolator actually understands in
@@ -31,6 +32,70 @@ var configSchema = map[string]map[string]string{
 	"security": {
 		"require_checksum": "bool",
 	},
+	"exec": {
+		"detach_keys": "string",
+	},
+	"proxy": {
+		"http":     "string",
+		"https":    "string",
+		"no_proxy": "string",
+	},
+	"registries": {
+		"search":   "array",
+		"blocked":  "array",
+		"mirrors":  "map",
+		"insecure": "array",
+		"ca_files": "map",
+	},
+	"pull": {
+		"retries":     "number",
+		"retry_delay": "string",
+		"timeout":     "string",
+	},
+	"container": {
+		"timezone":            "string",
+		"locale":              "string",
+		"runtime":             "string",
+		"passwd_entry":        "bool",
+		"passwd_file":         "string",
+		"group_file":          "string",
+		"containerenv":        "bool",
+		"hosts":               "bool",
+		"resolv_conf":         "bool",
+		"image_volumes":       "bool",
+		"machine_id":          "string",
+		"seccomp_profile":     "string",
+		"group_add":           "string",
+		"cap_drop_all":        "bool",
+		"cap_add":             "array",
+		"device_input":        "bool",
+		"device_kvm":          "bool",
+		"device_video":        "bool",
+		"privileged_devices":  "bool",
+		"mask_paths":          "array",
+		"default_mask_paths":  "bool",
+		"sysfs":               "string",
+		"device_fuse":         "bool",
+		"device_cgroup_rules": "array",
+		"cgroupns":            "string",
+		"cgroup_rw":           "bool",
+		"storage_size":        "string",
+		"mounts":              "array",
+		"device_read_iops":    "array",
+		"device_write_iops":   "array",
+		"cpu_shares":          "number",
+		"cpu_weight":          "number",
+		"memory_node_pin":     "string",
+		"umask":               "string",
+		"labels":              "array",
+		"annotations":         "array",
+		"label_namespace":     "string",
+		"ulimits":             "array",
+		"cgroup_conf":         "array",
+		"sysctls":             "array",
+		"proc_opts":           "string",
+		"network":             "string",
+	},
 }
 
 // ValidateConfigDoc checks doc against configSchema and returns one
@@ -45,6 +110,10 @@ func ValidateConfigDoc(doc *HkDocument) []string {
 	for _, secName := range doc.Sections.Keys() {
 		schema, knownSection := configSchema[secName]
 		secVal, _ := doc.Sections.Get(secName)
+		if secName == "profiles" && secVal.Kind == HkMapKind {
+			warnings = append(warnings, validateProfiles(secVal.MapVal)...)
+			continue
+		}
 		if !knownSection {
 			warnings = append(warnings, fmt.Sprintf("unknown config section [%s] — ignored", secName))
 			continue
@@ -68,6 +137,18 @@ func ValidateConfigDoc(doc *HkDocument) []string {
 				if v.Kind != HkString {
 					warnings = append(warnings, fmt.Sprintf("[%s] -> %s should be a plain string, got %s — using default", secName, key, hkKindName(v.Kind)))
 				}
+			case kind == "number":
+				if v.Kind != HkNumber {
+					warnings = append(warnings, fmt.Sprintf("[%s] -> %s should be a number, got %s — using default", secName, key, hkKindName(v.Kind)))
+				}
+			case kind == "array":
+				if v.Kind != HkArray {
+					warnings = append(warnings, fmt.Sprintf("[%s] -> %s should be an array like [a, b], got %s — using default", secName, key, hkKindName(v.Kind)))
+				}
+			case kind == "map":
+				if v.Kind != HkMapKind {
+					warnings = append(warnings, fmt.Sprintf("[%s] -> %s should be a map of --> entries, got %s — using default", secName, key, hkKindName(v.Kind)))
+				}
 			case strings.HasPrefix(kind, "enum:"):
 				options := strings.Split(strings.TrimPrefix(kind, "enum:"), ",")
 				s, err := v.AsString()
@@ -126,6 +207,70 @@ type Config struct {
 
 	// --- Safety -----------------------------------------------------------
 	RequireChecksum bool
+
+	// --- isolator exec -------------------------------------------------------
+	DetachKeys string // detach sequence for interactive sessions; "" disables
+
+	// --- Image pulls ----------------------------------------------------------
+	PullRetries    int    // extra attempts after a failed pull
+	PullRetryDelay string // wait before the first retry, doubling each time
+	PullTimeout    string // overall limit across all attempts; "" = none
+
+	// --- Registry mirrors, search and blocks (see registries.go) -------------
+	RegistrySearch   []string            // unqualified-search registries for short names
+	RegistryBlocked  []string            // registries pulls are refused from
+	RegistryMirrors  map[string][]string // upstream registry → mirrors, tried in order
+	RegistryInsecure []string            // registries reached without TLS verification
+	RegistryCAFiles  map[string]string   // registry → extra CA certificate file to trust
+
+	// --- Proxy for registry pulls and repo downloads (see proxy.go) ----------
+	ProxyHTTP  string // "" = leave HTTP_PROXY as the environment has it
+	ProxyHTTPS string
+	NoProxy    string
+
+	// --- Defaults for new containers (see RunOptions) ----------------------
+	Timezone          string // "" (image default, UTC) | "local" | "Zone/Name"
+	Locale            string // "" (image default) | "host"
+	Runtime           string // "" (podman's default) | "crun" | "runc" | ...
+	PasswdEntry       bool   // synthesize passwd/group entries for the mapped user
+	PasswdFile        string // host file mounted over /etc/passwd; "" = the image's
+	GroupFile         string // host file mounted over /etc/group; "" = the image's
+	ContainerEnv      bool   // keep podman's /run/.containerenv metadata file
+	Hosts             bool   // let podman generate /etc/hosts
+	ResolvConf        bool   // let podman generate /etc/resolv.conf
+	ImageVolumes      bool   // anonymous volumes for the image's VOLUME paths
+	MachineID         string // "" (generated per container) | "host" | "none"
+	SeccompProfile    string // "" (podman's default) | host path to a JSON profile | "unconfined"
+	GroupAdd          string // "" | "keep-groups"
+	CapDropAll        bool   // start with no capabilities (then CapAdd only)
+	CapAdd            []string
+	DeviceInput       bool // share gamepads/joysticks (/dev/input, /dev/uinput)
+	DeviceKVM         bool // share /dev/kvm, /dev/vhost-net, /dev/net/tun
+	DeviceVideo       bool // share webcams (/dev/video*, /dev/media*)
+	PrivilegedDevices bool // share GPU, video and sound nodes without --privileged
+	MaskPaths         []string
+	DefaultMaskPaths  bool   // keep podman's masks over /proc/kcore, /proc/keys, ...
+	SysFS             string // "" or "read-only" (podman's /sys masks) | "masked" | "unconfined"
+	DeviceFUSE        bool   // share /dev/fuse and allow FUSE mounts
+	DeviceCgroupRules []string
+	CgroupNS          string // "" (podman's default) | "private" | "host" | "auto"
+	CgroupRW          bool   // writable /sys/fs/cgroup (the container's own subtree)
+	StorageSize       string // "" (unlimited) | writable-layer cap like "20G"
+	Mounts            []string
+	DeviceReadIOPS    []string // "/dev/nvme0n1:1000" caps on reads per second
+	DeviceWriteIOPS   []string // ... and on writes per second
+	CPUShares         int      // 0 (default) | 2-262144, Docker's scale
+	CPUWeight         int      // 0 (default) | 1-10000, cgroup v2's scale
+	MemoryNodes       string   // "" (all nodes) | NUMA node list like "0" or "0-1"
+	Umask             string   // "" (podman's 0022) | octal mask like "0027"
+	Labels            []string // key=value container labels
+	Annotations       []string // key=value OCI annotations
+	LabelNamespace    string   // "" | reverse-DNS prefix for label keys, e.g. "com.example"
+	Ulimits           []string // TYPE=SOFT[:HARD]; core and nofile have built-in defaults
+	CgroupConf        []string // raw cgroup v2 writes, "cpu.idle=1"
+	Sysctls           []string // namespaced kernel parameters, "net.core.somaxconn=4096"
+	ProcOpts          string   // "" | /proc mount options like "hidepid=2"
+	Network           string   // "" (podman's NAT) | "macvlan:IFACE[:MODE]" | "ipvlan:IFACE[:MODE]"
 }
 
 func DefaultConfig() Config {
@@ -142,6 +287,52 @@ func DefaultConfig() Config {
 		AllowDesktopEnvironments: false,
 		AllowSystemContainers:    false,
 		RequireChecksum:          false,
+		DetachKeys:               DefaultDetachKeys,
+		PullRetries:              3,
+		PullRetryDelay:           "2s",
+		PullTimeout:              "",
+		Timezone:                 "",
+		Locale:                   "",
+		Runtime:                  "",
+		PasswdEntry:              true,
+		PasswdFile:               "",
+		GroupFile:                "",
+		ContainerEnv:             true,
+		Hosts:                    true,
+		ResolvConf:               true,
+		ImageVolumes:             true,
+		MachineID:                "",
+		SeccompProfile:           "",
+		GroupAdd:                 "",
+		CapDropAll:               false,
+		CapAdd:                   nil,
+		DeviceInput:              false,
+		DeviceKVM:                false,
+		DeviceVideo:              false,
+		PrivilegedDevices:        false,
+		MaskPaths:                nil,
+		DefaultMaskPaths:         true,
+		SysFS:                    "",
+		DeviceFUSE:               false,
+		DeviceCgroupRules:        nil,
+		CgroupNS:                 "",
+		CgroupRW:                 false,
+		StorageSize:              "",
+		Mounts:                   nil,
+		DeviceReadIOPS:           nil,
+		DeviceWriteIOPS:          nil,
+		CPUShares:                0,
+		CPUWeight:                0,
+		MemoryNodes:              "",
+		Umask:                    "",
+		Labels:                   nil,
+		Annotations:              nil,
+		LabelNamespace:           "",
+		Ulimits:                  nil,
+		CgroupConf:               nil,
+		Sysctls:                  nil,
+		ProcOpts:                 "",
+		Network:                  "",
 	}
 }
 
@@ -191,9 +382,132 @@ func LoadConfig() Config {
 	security := doc.Section("security")
 	cfg.RequireChecksum = hkGetBool(security, "require_checksum", cfg.RequireChecksum)
 
+	execSec := doc.Section("exec")
+	cfg.DetachKeys = hkGetString(execSec, "detach_keys", cfg.DetachKeys)
+	if _, err := ParseDetachKeys(cfg.DetachKeys); err != nil {
+		PrintWarn("config.hk: [exec] " + err.Error() + " — using " + DefaultDetachKeys)
+		cfg.DetachKeys = DefaultDetachKeys
+	}
+
+	pull := doc.Section("pull")
+	cfg.PullRetries = int(hkGetNumber(pull, "retries", float64(cfg.PullRetries)))
+	cfg.PullRetryDelay = hkGetString(pull, "retry_delay", cfg.PullRetryDelay)
+	cfg.PullTimeout = hkGetString(pull, "timeout", cfg.PullTimeout)
+	if cfg.PullRetries < 0 {
+		PrintWarn("config.hk: [pull] retries can't be negative — using 0")
+		cfg.PullRetries = 0
+	}
+	if d, err := time.ParseDuration(cfg.PullRetryDelay); err != nil || d < 0 {
+		PrintWarn(fmt.Sprintf("config.hk: [pull] retry_delay %q is not a duration like 2s — using 2s", cfg.PullRetryDelay))
+		cfg.PullRetryDelay = "2s"
+	}
+	if d, err := time.ParseDuration(cfg.PullTimeout); cfg.PullTimeout != "" && (err != nil || d <= 0) {
+		PrintWarn(fmt.Sprintf("config.hk: [pull] timeout %q is not a duration like 30m — pulling without a time limit", cfg.PullTimeout))
+		cfg.PullTimeout = ""
+	}
+
+	registries := doc.Section("registries")
+	cfg.RegistrySearch = hkGetStrings(registries, "search")
+	cfg.RegistryBlocked = hkGetStrings(registries, "blocked")
+	if v, ok := registries.Get("mirrors"); ok && v.Kind == HkMapKind {
+		cfg.RegistryMirrors = map[string][]string{}
+		for _, upstream := range v.MapVal.Keys() {
+			if stringInSlice(upstream, cfg.RegistryBlocked) {
+				PrintWarn(fmt.Sprintf("config.hk: [registries] %s is both blocked and mirrored — a blocked registry's mirrors aren't used either", upstream))
+			}
+			if mirrors := hkGetStrings(v.MapVal, upstream); len(mirrors) > 0 {
+				cfg.RegistryMirrors[upstream] = mirrors
+			}
+		}
+	}
+
+	cfg.RegistryInsecure = hkGetStrings(registries, "insecure")
+	if v, ok := registries.Get("ca_files"); ok && v.Kind == HkMapKind {
+		cfg.RegistryCAFiles = map[string]string{}
+		for _, r := range v.MapVal.Keys() {
+			if stringInSlice(r, cfg.RegistryInsecure) {
+				PrintWarn(fmt.Sprintf("config.hk: [registries] %s is both insecure and given a CA file — its certificate isn't checked at all, so the CA file has no effect", r))
+			}
+			if path := hkGetString(v.MapVal, r, ""); path != "" {
+				cfg.RegistryCAFiles[r] = path
+			}
+		}
+	}
+
+	proxy := doc.Section("proxy")
+	cfg.ProxyHTTP = hkGetString(proxy, "http", cfg.ProxyHTTP)
+	cfg.ProxyHTTPS = hkGetString(proxy, "https", cfg.ProxyHTTPS)
+	cfg.NoProxy = hkGetString(proxy, "no_proxy", cfg.NoProxy)
+	if err := validateProxyURL(cfg.ProxyHTTP); cfg.ProxyHTTP != "" && err != nil {
+		PrintWarn("config.hk: [proxy] http " + err.Error() + " — ignoring it")
+		cfg.ProxyHTTP = ""
+	}
+	if err := validateProxyURL(cfg.ProxyHTTPS); cfg.ProxyHTTPS != "" && err != nil {
+		PrintWarn("config.hk: [proxy] https " + err.Error() + " — ignoring it")
+		cfg.ProxyHTTPS = ""
+	}
+
+	// [container] applies as a whole or not at all: a partly applied set
+	// could keep cap_drop_all while dropping the cap_add it was paired
+	// with. cfg still holds DefaultConfig's values for all of it.
+	withContainer := cfg
+	loadContainerSection(&withContainer, doc.Section("container"))
+	if err := RunOptionsFromConfig(withContainer).Validate(); err != nil {
+		PrintWarn("config.hk: [container] " + err.Error() + " — using defaults")
+	} else {
+		cfg = withContainer
+	}
+
 	return cfg
 }
 
+// loadContainerSection reads the [container] defaults for new containers
+// into cfg, keeping cfg's value for each key that isn't set.
+func loadContainerSection(cfg *Config, container *HkMap) {
+	cfg.Timezone = hkGetString(container, "timezone", cfg.Timezone)
+	cfg.Locale = hkGetString(container, "locale", cfg.Locale)
+	cfg.Runtime = hkGetString(container, "runtime", cfg.Runtime)
+	cfg.PasswdEntry = hkGetBool(container, "passwd_entry", cfg.PasswdEntry)
+	cfg.PasswdFile = hkGetString(container, "passwd_file", cfg.PasswdFile)
+	cfg.GroupFile = hkGetString(container, "group_file", cfg.GroupFile)
+	cfg.ContainerEnv = hkGetBool(container, "containerenv", cfg.ContainerEnv)
+	cfg.Hosts = hkGetBool(container, "hosts", cfg.Hosts)
+	cfg.ResolvConf = hkGetBool(container, "resolv_conf", cfg.ResolvConf)
+	cfg.ImageVolumes = hkGetBool(container, "image_volumes", cfg.ImageVolumes)
+	cfg.MachineID = hkGetString(container, "machine_id", cfg.MachineID)
+	cfg.SeccompProfile = hkGetString(container, "seccomp_profile", cfg.SeccompProfile)
+	cfg.GroupAdd = hkGetString(container, "group_add", cfg.GroupAdd)
+	cfg.CapDropAll = hkGetBool(container, "cap_drop_all", cfg.CapDropAll)
+	cfg.CapAdd = hkGetStrings(container, "cap_add")
+	cfg.DeviceInput = hkGetBool(container, "device_input", cfg.DeviceInput)
+	cfg.DeviceKVM = hkGetBool(container, "device_kvm", cfg.DeviceKVM)
+	cfg.DeviceVideo = hkGetBool(container, "device_video", cfg.DeviceVideo)
+	cfg.PrivilegedDevices = hkGetBool(container, "privileged_devices", cfg.PrivilegedDevices)
+	cfg.MaskPaths = hkGetStrings(container, "mask_paths")
+	cfg.DefaultMaskPaths = hkGetBool(container, "default_mask_paths", cfg.DefaultMaskPaths)
+	cfg.SysFS = hkGetString(container, "sysfs", cfg.SysFS)
+	cfg.DeviceFUSE = hkGetBool(container, "device_fuse", cfg.DeviceFUSE)
+	cfg.DeviceCgroupRules = hkGetStrings(container, "device_cgroup_rules")
+	cfg.CgroupNS = hkGetString(container, "cgroupns", cfg.CgroupNS)
+	cfg.CgroupRW = hkGetBool(container, "cgroup_rw", cfg.CgroupRW)
+	cfg.StorageSize = hkGetString(container, "storage_size", cfg.StorageSize)
+	cfg.Mounts = hkGetStrings(container, "mounts")
+	cfg.DeviceReadIOPS = hkGetStrings(container, "device_read_iops")
+	cfg.DeviceWriteIOPS = hkGetStrings(container, "device_write_iops")
+	cfg.CPUShares = int(hkGetNumber(container, "cpu_shares", float64(cfg.CPUShares)))
+	cfg.CPUWeight = int(hkGetNumber(container, "cpu_weight", float64(cfg.CPUWeight)))
+	cfg.MemoryNodes = hkGetString(container, "memory_node_pin", cfg.MemoryNodes)
+	cfg.Umask = hkGetString(container, "umask", cfg.Umask)
+	cfg.Labels = hkGetStrings(container, "labels")
+	cfg.Annotations = hkGetStrings(container, "annotations")
+	cfg.LabelNamespace = hkGetString(container, "label_namespace", cfg.LabelNamespace)
+	cfg.Ulimits = hkGetStrings(container, "ulimits")
+	cfg.CgroupConf = hkGetStrings(container, "cgroup_conf")
+	cfg.Sysctls = hkGetStrings(container, "sysctls")
+	cfg.ProcOpts = hkGetString(container, "proc_opts", cfg.ProcOpts)
+	cfg.Network = hkGetString(container, "network", cfg.Network)
+}
+
 // SaveConfig writes cfg to config.hk atomically.
 func SaveConfig(cfg Config) error {
 	if err := EnsureConfigDir(); err != nil {
@@ -220,5 +534,77 @@ func SaveConfig(cfg Config) error {
 	security := doc.Section("security")
 	security.Set("require_checksum", hkBoolV(cfg.RequireChecksum))
 
+	execSec := doc.Section("exec")
+	execSec.Set("detach_keys", hkStr(cfg.DetachKeys))
+
+	pull := doc.Section("pull")
+	pull.Set("retries", hkNum(float64(cfg.PullRetries)))
+	pull.Set("retry_delay", hkStr(cfg.PullRetryDelay))
+	pull.Set("timeout", hkStr(cfg.PullTimeout))
+
+	registries := doc.Section("registries")
+	registries.Set("search", hkStrs(cfg.RegistrySearch))
+	registries.Set("blocked", hkStrs(cfg.RegistryBlocked))
+	mirrors := NewHkMap()
+	for _, upstream := range mirroredRegistries(cfg) {
+		mirrors.Set(upstream, hkStrs(cfg.RegistryMirrors[upstream]))
+	}
+	registries.Set("mirrors", HkValue{Kind: HkMapKind, MapVal: mirrors})
+	registries.Set("insecure", hkStrs(cfg.RegistryInsecure))
+	caFiles := NewHkMap()
+	for _, r := range sortedRegistryCAs(cfg) {
+		caFiles.Set(r, hkStr(cfg.RegistryCAFiles[r]))
+	}
+	registries.Set("ca_files", HkValue{Kind: HkMapKind, MapVal: caFiles})
+
+	proxy := doc.Section("proxy")
+	proxy.Set("http", hkStr(cfg.ProxyHTTP))
+	proxy.Set("https", hkStr(cfg.ProxyHTTPS))
+	proxy.Set("no_proxy", hkStr(cfg.NoProxy))
+
+	container := doc.Section("container")
+	container.Set("timezone", hkStr(cfg.Timezone))
+	container.Set("locale", hkStr(cfg.Locale))
+	container.Set("runtime", hkStr(cfg.Runtime))
+	container.Set("passwd_entry", hkBoolV(cfg.PasswdEntry))
+	container.Set("passwd_file", hkStr(cfg.PasswdFile))
+	container.Set("group_file", hkStr(cfg.GroupFile))
+	container.Set("containerenv", hkBoolV(cfg.ContainerEnv))
+	container.Set("hosts", hkBoolV(cfg.Hosts))
+	container.Set("resolv_conf", hkBoolV(cfg.ResolvConf))
+	container.Set("image_volumes", hkBoolV(cfg.ImageVolumes))
+	container.Set("machine_id", hkStr(cfg.MachineID))
+	container.Set("seccomp_profile", hkStr(cfg.SeccompProfile))
+	container.Set("group_add", hkStr(cfg.GroupAdd))
+	container.Set("cap_drop_all", hkBoolV(cfg.CapDropAll))
+	container.Set("cap_add", hkStrs(cfg.CapAdd))
+	container.Set("device_input", hkBoolV(cfg.DeviceInput))
+	container.Set("device_kvm", hkBoolV(cfg.DeviceKVM))
+	container.Set("device_video", hkBoolV(cfg.DeviceVideo))
+	container.Set("privileged_devices", hkBoolV(cfg.PrivilegedDevices))
+	container.Set("mask_paths", hkStrs(cfg.MaskPaths))
+	container.Set("default_mask_paths", hkBoolV(cfg.DefaultMaskPaths))
+	container.Set("sysfs", hkStr(cfg.SysFS))
+	container.Set("device_fuse", hkBoolV(cfg.DeviceFUSE))
+	container.Set("device_cgroup_rules", hkStrs(cfg.DeviceCgroupRules))
+	container.Set("cgroupns", hkStr(cfg.CgroupNS))
+	container.Set("cgroup_rw", hkBoolV(cfg.CgroupRW))
+	container.Set("storage_size", hkStr(cfg.StorageSize))
+	container.Set("mounts", hkStrs(cfg.Mounts))
+	container.Set("device_read_iops", hkStrs(cfg.DeviceReadIOPS))
+	container.Set("device_write_iops", hkStrs(cfg.DeviceWriteIOPS))
+	container.Set("cpu_shares", hkNum(float64(cfg.CPUShares)))
+	container.Set("cpu_weight", hkNum(float64(cfg.CPUWeight)))
+	container.Set("memory_node_pin", hkStr(cfg.MemoryNodes))
+	container.Set("umask", hkStr(cfg.Umask))
+	container.Set("labels", hkStrs(cfg.Labels))
+	container.Set("annotations", hkStrs(cfg.Annotations))
+	container.Set("label_namespace", hkStr(cfg.LabelNamespace))
+	container.Set("ulimits", hkStrs(cfg.Ulimits))
+	container.Set("cgroup_conf", hkStrs(cfg.CgroupConf))
+	container.Set("sysctls", hkStrs(cfg.Sysctls))
+	container.Set("proc_opts", hkStr(cfg.ProcOpts))
+	container.Set("network", hkStr(cfg.Network))
+
 	return WriteHKFile(configFilePath(), doc)
 }
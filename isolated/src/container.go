@@ -1,20 +1,44 @@
 package src
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/briandowns/spinner"
 )
 
-// CheckPodman verifies podman is available.
+// otherEngines are container tools CheckPodman points out when podman is
+// missing. Neither can stand in for it: Isolator's containers depend on
+// rootless podman features docker lacks (--userns=keep-id, --umask,
+// --cgroup-conf, exec --detach-keys, search --list-tags), and skopeo only
+// copies images — it can't run a container at all.
+var otherEngines = map[string]string{
+	"docker": "docker is installed, but Isolator's containers rely on rootless podman features (keep-id user namespaces among them) that docker doesn't have",
+	"skopeo": "skopeo is installed, but it only copies images and can't run a container",
+}
+
+// CheckPodman verifies podman is available, and otherwise says what was
+// looked for and how to install it.
 func CheckPodman() error {
-	_, err := exec.LookPath(podmanBin)
-	return err
+	if _, err := exec.LookPath(podmanBin); err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("%s not found in PATH (%s). Isolator runs every package in a podman container; install it with your distro's package manager, e.g. 'sudo apt install podman' or 'sudo dnf install podman'", podmanBin, os.Getenv("PATH"))
+	for _, name := range []string{"docker", "skopeo"} {
+		if _, err := exec.LookPath(name); err == nil {
+			msg += "\n  " + otherEngines[name]
+		}
+	}
+	return errors.New(msg)
 }
 
 // PullImage pulls image with a visible progress spinner, independent of
@@ -25,25 +49,93 @@ func CheckPodman() error {
 // `--pull missing` instead of `--pull always`, so this is a pure addition
 // of feedback, not a behavior change — the net result (fresh image if
 // needed, cached reuse otherwise) is the same as before.
+//
+// A failed pull is retried ([pull] -> retries, with a delay that starts at
+// retry_delay and doubles). Podman commits each layer to local storage as
+// soon as it's downloaded, so a retry only fetches what the failed attempt
+// didn't finish. [pull] -> timeout bounds all attempts together, and
+// Ctrl-C stops at once instead of moving on to the next attempt.
+//
+// [registries] settings are synced into podman's registries.conf.d first.
+// With mirrors configured the pull runs at podman's debug log level, which
+// is the only place podman says which mirror (or the upstream) it got the
+// image from.
 func PullImage(image string) bool {
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	s.Suffix = fmt.Sprintf(" Pulling image %s...", image)
-	s.Color("cyan")
-	s.Start()
-
-	cmd := exec.Command(podmanBin, "pull", image)
-	output, err := cmd.CombinedOutput()
-	s.Stop()
+	cfg := LoadConfig()
+	if err := checkRegistryPolicy(cfg, image); err != nil {
+		PrintError(err.Error())
+		return false
+	}
+	if err := SyncRegistriesConf(cfg); err != nil {
+		PrintWarn(fmt.Sprintf("Couldn't write %s (%v) — [registries] settings won't apply to this pull", registriesDropInPath(), err))
+	}
+	if err := SyncRegistryCAs(cfg); err != nil {
+		PrintWarn(fmt.Sprintf("Couldn't install the [registries] CA files in %s (%v) — pulls from those registries may fail certificate checks", registryCertsDir(), err))
+	}
+	pullArgs := []string{"pull", image}
+	traceSource := len(cfg.RegistryMirrors) > 0
+	if traceSource {
+		pullArgs = append([]string{"--log-level=debug"}, pullArgs...)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeout, err := time.ParseDuration(cfg.PullTimeout); err == nil && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	delay, _ := time.ParseDuration(cfg.PullRetryDelay)
 
-	if err != nil {
-		PrintError(fmt.Sprintf("Failed to pull image %s", image))
-		if len(output) > 0 {
-			fmt.Println(DimStyle.Render(string(output)))
+	attempts := cfg.PullRetries + 1
+	var output []byte
+	for attempt := 1; attempt <= attempts; attempt++ {
+		s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+		s.Suffix = fmt.Sprintf(" Pulling image %s...", image)
+		if attempt > 1 {
+			s.Suffix = fmt.Sprintf(" Pulling image %s (attempt %d/%d)...", image, attempt, attempts)
 		}
-		return false
+		s.Color("cyan")
+		s.Start()
+		var err error
+		output, err = exec.CommandContext(ctx, podmanBin, pullArgs...).CombinedOutput()
+		s.Stop()
+		if err == nil {
+			PrintSuccess(fmt.Sprintf("Image ready: %s", image))
+			if source := pulledFrom(output); traceSource && source != "" {
+				fmt.Println(DimStyle.Render("  pulled from " + source))
+			}
+			return true
+		}
+		if ctx.Err() != nil || attempt == attempts {
+			break
+		}
+		PrintWarn(fmt.Sprintf("Pull of %s failed, retrying in %s", image, delay))
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		delay = min(delay*2, time.Minute)
 	}
-	PrintSuccess(fmt.Sprintf("Image ready: %s", image))
-	return true
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		PrintError(fmt.Sprintf("Pulling image %s timed out after %s ([pull] -> timeout in config.hk)", image, cfg.PullTimeout))
+	case ctx.Err() != nil:
+		PrintError(fmt.Sprintf("Pulling image %s was interrupted", image))
+	default:
+		PrintError(fmt.Sprintf("Failed to pull image %s after %d attempt(s)", image, attempts))
+	}
+	// Podman's output names the blob it was copying when it failed.
+	if traceSource {
+		output = withoutDebugLines(output)
+	}
+	if len(output) > 0 {
+		fmt.Println(DimStyle.Render(string(output)))
+	}
+	return false
 }
 
 // GetContainers returns list of all Podman containers (JSON).
@@ -60,6 +152,11 @@ func GetContainers() []ContainerInfo {
 	return list
 }
 
+// ImageExists reports whether podman has a local image with this name or tag.
+func ImageExists(ref string) bool {
+	return exec.Command(podmanBin, "image", "exists", ref).Run() == nil
+}
+
 func ContainerExists(name string) bool {
 	for _, c := range GetContainers() {
 		for _, n := range c.Names {
@@ -99,19 +196,36 @@ func GetContainerSize(name string) string {
 	return list[0].Size
 }
 
+// GetContainerRuntime returns the OCI runtime podman runs name with (e.g.
+// "crun", "runc"), as recorded by podman itself when the container was
+// created — so it's right even for containers made before Isolator
+// tracked --runtime, or with podman's default.
+func GetContainerRuntime(name string) string {
+	out, err := exec.Command(podmanBin, "inspect", "--format", "{{.OCIRuntime}}", name).Output()
+	if err != nil {
+		if opts, _ := LoadContainerOptions(name); opts.Runtime != "" {
+			return filepath.Base(opts.Runtime)
+		}
+		return "unknown"
+	}
+	return filepath.Base(strings.TrimSpace(string(out)))
+}
+
 // getPodmanRunArgs builds arguments for podman run -d.
 // GUI/audio/GPU/theme/desktop-environment support is delegated to
 // BuildGraphicsArgs (gui.go), which is driven by the user's config and by
 // what's actually detected on the host, instead of blindly mounting
-// everything for every container type.
-func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string) []string {
+// everything for every container type. User-chosen per-container settings
+// (timezone, locale, ...) come from opts — see runopts.go.
+func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string, opts RunOptions) []string {
 	uid := os.Getuid()
 	gid := os.Getgid()
 	homeHost := homeDir
 	if homeHost == "" {
 		homeHost = os.Getenv("HOME")
 	}
-	args := []string{
+	// --runtime is a podman *global* flag, so it has to precede "run".
+	args := append(buildRuntimeArgs(opts.Runtime),
 		"run", "-d",
 		"--name", name,
 		"--hostname", name,
@@ -121,6 +235,9 @@ func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string) []string
 		"--workdir", "/home/user",
 		"--env", "HOME=/home/user",
 		"--env", fmt.Sprintf("USER=%s", os.Getenv("USER")),
+	)
+	for _, kv := range ContainerIdentityEnv(name, containerSource(image, opts)) {
+		args = append(args, "--env", kv)
 	}
 
 	// Mount home directory
@@ -134,7 +251,12 @@ func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string) []string
 		cfg:        cfg,
 		pkgType:    pkgType,
 		initSystem: initSystem,
+		image:      image,
+		opts:       opts,
 	})...)
+	args = append(args, BuildRunOptionArgs(opts)...)
+	args = append(args, buildMachineIDArgs(name, opts.MachineID)...)
+	args = append(args, buildMACAddressArgs(name, opts.Network, opts.MACAddress)...)
 
 	// SELinux (if enabled) – may be needed for X11
 	args = append(args, "--security-opt", "label=type:container_runtime_t")
@@ -143,10 +265,18 @@ func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string) []string
 	// several of its tools (raw sockets, ptrace-based tools, etc.) trip the
 	// default Podman seccomp filter otherwise. See:
 	// https://hub.docker.com/r/blackarchlinux/blackarch
-	if strings.Contains(image, "blackarch") {
+	if strings.Contains(image, "blackarch") && opts.SeccompProfile == "" {
 		args = append(args, "--security-opt", "seccomp=unconfined")
 	}
 
+	args = dedupeDeviceArgs(dedupeGroupAddArgs(args))
+
+	// With --rootfs, podman takes the tree's path where the image would go.
+	if opts.RootFS != "" {
+		args = append(args, "--rootfs")
+		image = opts.RootFS
+	}
+
 	// Keep the container alive with a dummy command
 	args = append(args, "--entrypoint", "/bin/sh")
 	args = append(args, image, "-c", "while true; do sleep 1000; done")
@@ -154,19 +284,60 @@ func getPodmanRunArgs(name, image, homeDir, pkgType, initSystem string) []string
 	return args
 }
 
+// ContainerIdentityEnv is the set of variables that tell processes inside
+// a container that they're in one, and which: "container" (the variable
+// systemd-detect-virt and friends look at — podman would otherwise set it
+// to "podman"), plus the Isolator container name and the image it was
+// created from. Podman's /run/.containerenv carries the same details
+// (plus the podman container ID) as key=value lines, for tools that read
+// that instead.
+func ContainerIdentityEnv(name, image string) []string {
+	return []string{
+		"container=isolator",
+		"ISOLATOR_NAME=" + name,
+		"ISOLATOR_IMAGE=" + image,
+	}
+}
+
 // CreateContainer creates a Podman container and starts it with a persistent dummy command.
 // Returns true on success, false otherwise.
-func CreateContainer(name, image, homeDir, pkgType, initSystem string) bool {
-	if !PullImage(image) {
+func CreateContainer(name, image, homeDir, pkgType, initSystem string, opts RunOptions) bool {
+	if opts.RootFS == "" && !PullImage(image) {
+		return false
+	}
+	// Joining a namespace needs its owner running (rollbackOne checks the
+	// same before recreating a container).
+	if target := opts.PIDTarget(); target != "" && !EnsureContainerRunning(target) {
+		PrintError(fmt.Sprintf("--pid: container '%s' doesn't exist or couldn't be started", target))
 		return false
 	}
-	args := getPodmanRunArgs(name, image, homeDir, pkgType, initSystem)
-	PrintStep(fmt.Sprintf("Creating container %s (image: %s)...", name, image))
+	if opts.Network != "" {
+		if _, err := ensureL2Network(opts.Network, opts.IP); err != nil {
+			PrintError(err.Error())
+			return false
+		}
+	}
+	if opts.MachineID == "" {
+		if err := ensureMachineID(name); err != nil {
+			PrintError(err.Error())
+			return false
+		}
+	}
+	args := getPodmanRunArgs(name, image, homeDir, pkgType, initSystem, opts)
+	source := "image: " + image
+	if opts.RootFS != "" {
+		source = "rootfs: " + containerSource(image, opts)
+	}
+	PrintStep(fmt.Sprintf("Creating container %s (%s)...", name, source))
 	if !ExecCommand(podmanBin, args) {
 		// If run fails, try to remove any leftover container
-		ExecCommand(podmanBin, []string{"rm", "--force", name})
+		ExecCommand(podmanBin, []string{"rm", "--force", "--volumes", name})
+		explainCreateFailure()
 		return false
 	}
+	if err := SaveContainerOptions(name, opts); err != nil {
+		PrintWarn("Container created, but failed to record its run options: " + err.Error())
+	}
 	PrintSuccess(fmt.Sprintf("Container '%s' created and started", name))
 	return true
 }
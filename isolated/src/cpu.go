@@ -0,0 +1,58 @@
+package src
+
+import (
+	"fmt"
+)
+
+// CPU weight has two scales. cgroup v1's cpu.shares (and Docker's
+// --cpu-shares) run from 2 to 262144 with 1024 as the default; cgroup v2's
+// cpu.weight runs from 1 to 10000 with 100 as the default. They are not
+// the same number: 1024 shares is 1 + 1022*9999/262142 = 39 in weight,
+// not 100, so a share value written straight into cpu.weight (or a weight
+// passed as shares) gives a container a very different slice of the CPU
+// on v1 and v2 hosts. Podman converts --cpu-shares to a weight itself on
+// v2; a --cpu-weight is converted to shares on v1 with the inverse of the
+// mapping crun and runc use:
+//
+//	weight = 1 + (shares - 2) * 9999 / 262142
+//	shares = 2 + (weight - 1) * 262142 / 9999 (rounded up)
+const (
+	minCPUShares = 2
+	maxCPUShares = 262144
+	minCPUWeight = 1
+	maxCPUWeight = 10000
+)
+
+// cpuSharesFromWeight rounds up, so converting back gives the same weight.
+func cpuSharesFromWeight(weight int) int {
+	return 2 + ((weight-1)*262142+9998)/9999
+}
+
+func validateCPUShare(shares, weight int) error {
+	if shares != 0 && weight != 0 {
+		return fmt.Errorf("--cpu-shares and --cpu-weight set the same thing on different scales; use one")
+	}
+	if shares != 0 && (shares < minCPUShares || shares > maxCPUShares) {
+		return fmt.Errorf("--cpu-shares %d is out of range (%d-%d, default 1024)", shares, minCPUShares, maxCPUShares)
+	}
+	if weight != 0 && (weight < minCPUWeight || weight > maxCPUWeight) {
+		return fmt.Errorf("--cpu-weight %d is out of range (%d-%d, default 100)", weight, minCPUWeight, maxCPUWeight)
+	}
+	return nil
+}
+
+// buildCPUArgs passes the relative CPU weight on. Podman's --cpu-shares
+// already converts to cpu.weight on v2 hosts, so shares go through as is;
+// a weight is written to cpu.weight directly on v2, and converted back to
+// shares on v1, where cpu.weight doesn't exist.
+func buildCPUArgs(shares, weight int) []string {
+	switch {
+	case shares != 0:
+		return []string{fmt.Sprintf("--cpu-shares=%d", shares)}
+	case weight != 0 && hostCgroupV2():
+		return []string{fmt.Sprintf("--cgroup-conf=cpu.weight=%d", weight)}
+	case weight != 0:
+		return []string{fmt.Sprintf("--cpu-shares=%d", cpuSharesFromWeight(weight))}
+	}
+	return nil
+}
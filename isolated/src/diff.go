@@ -0,0 +1,110 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// fsChange is one line of `isolator diff`, also its --format json shape.
+type fsChange struct {
+	Kind string `json:"kind"` // "A" added, "C" changed, "D" deleted
+	Path string `json:"path"`
+}
+
+// containerChanges asks podman what cont's writable layer holds compared
+// to its image. Podman reads that from the storage driver's own layer
+// diff — for overlay, the upper directory, with whiteouts reported as
+// deletions — so bind mounts (the home directory among them) never show
+// up. The result is sorted by path, then kind.
+func containerChanges(cont string) ([]fsChange, error) {
+	out, err := exec.Command(podmanBin, "diff", "--format", "json", cont).Output()
+	if err != nil {
+		return nil, fmt.Errorf("podman diff %s failed: %v", cont, err)
+	}
+	var raw struct {
+		Changed []string `json:"changed"`
+		Added   []string `json:"added"`
+		Deleted []string `json:"deleted"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("unexpected output from podman diff: %v", err)
+	}
+	var changes []fsChange
+	for _, group := range []struct {
+		kind  string
+		paths []string
+	}{{"A", raw.Added}, {"C", raw.Changed}, {"D", raw.Deleted}} {
+		for _, p := range group.paths {
+			changes = append(changes, fsChange{Kind: group.kind, Path: p})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes, nil
+}
+
+// changesUnder keeps the changes at or below subtree ("" keeps them all).
+func changesUnder(changes []fsChange, subtree string) []fsChange {
+	if subtree == "" || subtree == "/" {
+		return changes
+	}
+	subtree = path.Clean("/" + subtree)
+	var kept []fsChange
+	for _, c := range changes {
+		if c.Path == subtree || strings.HasPrefix(c.Path, subtree+"/") {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// HandleDiff lists the paths a container added (A), changed (C) or
+// deleted (D) relative to its image, optionally only under subtree.
+func HandleDiff(cont, subtree, format string) {
+	if format != "" && format != "json" {
+		PrintError(fmt.Sprintf("--format %q is not supported (expected json, or leave unset for a list)", format))
+		os.Exit(1)
+	}
+	if !ContainerExists(cont) {
+		PrintError(fmt.Sprintf("Container '%s' not found", cont))
+		os.Exit(1)
+	}
+	changes, err := containerChanges(cont)
+	if err != nil {
+		PrintError(err.Error())
+		os.Exit(1)
+	}
+	changes = changesUnder(changes, subtree)
+
+	if format == "json" {
+		if changes == nil {
+			changes = []fsChange{}
+		}
+		out, _ := json.MarshalIndent(changes, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(changes) == 0 {
+		PrintInfo("No changes")
+		return
+	}
+	for _, c := range changes {
+		style := SuccessStyle
+		switch c.Kind {
+		case "C":
+			style = WarnStyle
+		case "D":
+			style = ErrorStyle
+		}
+		fmt.Printf("%s %s\n", style.Render(c.Kind), c.Path)
+	}
+}
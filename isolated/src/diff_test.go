@@ -0,0 +1,14 @@
+package src
+
+import "testing"
+
+func TestChangesUnder(t *testing.T) {
+	changes := []fsChange{{"C", "/etc"}, {"A", "/etc/foo.conf"}, {"D", "/etcetera"}, {"A", "/usr/bin/tool"}}
+	got := changesUnder(changes, "/etc/")
+	if len(got) != 2 || got[0].Path != "/etc" || got[1].Path != "/etc/foo.conf" {
+		t.Errorf("changesUnder(/etc/) = %v, want /etc and /etc/foo.conf only", got)
+	}
+	if got := changesUnder(changes, ""); len(got) != len(changes) {
+		t.Errorf("changesUnder(\"\") dropped entries: %v", got)
+	}
+}
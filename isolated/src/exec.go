@@ -1,6 +1,7 @@
 package src
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -18,6 +19,35 @@ func ExecCommand(bin string, args []string) bool {
 	return cmd.Run() == nil
 }
 
+// ExecCommandNoStdin is ExecCommand without the caller's stdin attached:
+// the command reads EOF straight away instead of competing with the
+// parent for whatever is piped into it.
+func ExecCommandNoStdin(bin string, args []string) bool {
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run() == nil
+}
+
+// ExecCommandExitCode runs bin like ExecCommand (or ExecCommandNoStdin,
+// without stdin) and returns its exit code. err is only set when bin
+// couldn't be run at all; a command that ran and failed just has a
+// non-zero code.
+func ExecCommandExitCode(bin string, args []string, stdin bool) (int, error) {
+	cmd := exec.Command(bin, args...)
+	if stdin {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
 // ExecInContainer runs a command inside a container.
 // If asRoot is true, the command is executed as root (UID 0) inside the container.
 // Otherwise, it runs as the default user (the one mapped via --userns=keep-id).
@@ -18,18 +18,24 @@ func PrintColoredHelp() {
 		{"install", "<pkg>", "Install a package into a Podman container"},
 		{"remove", "<pkg>", "Remove an installed package"},
 		{"exec", "<pkg> -- <cmd>", "Run an arbitrary command inside a package's container"},
+		{"shell", "<pkg|container>", "Open an interactive shell (bash, else ash, else sh) in a container"},
 		{"search", "<term>", "Fuzzy-search the repository for packages"},
 		{"info", "<pkg>", "Show detailed info about a package"},
 		{"list", "", "List all installed packages"},
-		{"status", "", "Show container status dashboard"},
+		{"status", "[-w] [--filter annotation=K[=V]]", "Show container status dashboard (-w: keep it updating live)"},
+		{"tags", "<image>", "List an image repository's tags, newest first (--limit N, --format json)"},
 		{"update", "", "Update packages in all managed containers"},
 		{"refresh", "", "Force re-download of the repository list"},
 		{"upgrade", "", "Full system upgrade (host + containers)"},
 		{"autoremove", "", "Remove orphaned containers with no packages left"},
 		{"clean", "", "Prune dangling Podman images and build cache"},
+		{"system reset", "[--force] [--keep-images] [--homes]", "Remove every Isolator container, snapshot and state file"},
 		{"snapshot", "<container>", "Save a rollback point for a container"},
 		{"rollback", "<container>", "Restore a container from its latest snapshot"},
 		{"snapshots", "", "List saved snapshots"},
+		{"diff", "<container> [path]", "List files a container added (A), changed (C) or deleted (D)"},
+		{"chown", "<rootfs> [--dry-run]", "Shift a --rootfs tree's owners into your user namespace (with sudo)"},
+		{"config profiles", "", "List install profiles defined in config.hk"},
 	}
 	for _, c := range cmds {
 		args := ""
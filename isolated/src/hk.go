@@ -703,6 +703,43 @@ func hkGetBool(m *HkMap, key string, def bool) bool {
 	return def
 }
 
+func hkGetNumber(m *HkMap, key string, def float64) float64 {
+	if v, ok := m.Get(key); ok {
+		if n, err := v.AsNumber(); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// hkGetStrings returns the string elements of an array value, or nil if
+// key is missing, isn't an array, or the array is empty.
+func hkGetStrings(m *HkMap, key string) []string {
+	v, ok := m.Get(key)
+	if !ok {
+		return nil
+	}
+	arr, err := v.AsArray()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, item := range arr {
+		if s, err := item.AsString(); err == nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func hkStrs(ss []string) HkValue {
+	arr := make([]HkValue, len(ss))
+	for i, s := range ss {
+		arr[i] = hkStr(s)
+	}
+	return HkValue{Kind: HkArray, Arr: arr}
+}
+
 func hkStr(s string) HkValue  { return HkValue{Kind: HkString, Str: s} }
 func hkBoolV(b bool) HkValue  { return HkValue{Kind: HkBool, Bool: b} }
 func hkNum(n float64) HkValue { return HkValue{Kind: HkNumber, Num: n} }
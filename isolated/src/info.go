@@ -38,6 +38,20 @@ func HandleInfo(pkg string) {
 					}
 					fmt.Printf("  %s  %s\n", BoldStyle.Render("Status: "), SuccessStyle.Render("installed"+iso))
 					fmt.Printf("  %s  %s\n", BoldStyle.Render("Cont:   "), ip.Cont)
+					if d, ok := Distros[ip.Distro]; ok {
+						opts, _ := LoadContainerOptions(ip.Cont)
+						if opts.RootFS != "" {
+							fmt.Printf("  %s  %s\n", BoldStyle.Render("Rootfs: "), opts.RootFS)
+						}
+						injected := strings.Join(ContainerIdentityEnv(ip.Cont, containerSource(d.Image, opts)), " ")
+						if !opts.NoContainerEnv {
+							injected += DimStyle.Render(" + /run/.containerenv")
+						}
+						fmt.Printf("  %s  %s\n", BoldStyle.Render("Env:    "), injected)
+					}
+					if opts, _ := LoadContainerOptions(ip.Cont); len(opts.Publish) > 0 {
+						fmt.Printf("  %s  %s\n", BoldStyle.Render("Ports:  "), strings.Join(opts.Publish, ", "))
+					}
 					fmt.Println()
 					return
 				}
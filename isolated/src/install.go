@@ -22,11 +22,50 @@ func boolLabelStr(b bool, ifTrue, ifFalse string) string {
 	return ifFalse
 }
 
-func HandleInstall(pkg string, isolated bool, dryRun bool) {
+// warnRunOptionsMismatch tells the user when the run options they asked
+// for differ from the ones an existing (reused) container was created with
+// — those only apply at creation time, so they'd otherwise be silently
+// ignored.
+func warnRunOptionsMismatch(contName string, opts RunOptions) {
+	existing, ok := LoadContainerOptions(contName)
+	if !ok {
+		// Created before options were recorded, with what are now
+		// defaults.
+		existing = RunOptions{}
+	}
+	if existing.Equal(opts) {
+		return
+	}
+	have := strings.Join(existing.Summary(), ", ")
+	if have == "" {
+		have = "defaults"
+	}
+	PrintWarn(fmt.Sprintf("Container '%s' was created with different run options (%s) — they only apply when a container is created, so this install uses the existing ones. Remove the container first to recreate it with the new options.", contName, have))
+}
+
+func HandleInstall(pkg string, isolated bool, dryRun bool, opts RunOptions) {
 	if err := ValidatePackageName(pkg); err != nil {
 		PrintError(err.Error())
 		return
 	}
+	if err := opts.Validate(); err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if err := opts.CheckDevices(); err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if target := opts.PIDTarget(); target != "" && !ContainerExists(target) {
+		PrintError(fmt.Sprintf("--pid: no container named '%s' (see 'isolator status')", target))
+		return
+	}
+	for _, w := range opts.HostWarnings() {
+		PrintWarn(w)
+	}
+	for _, w := range annotationWarnings(opts.Annotations) {
+		PrintWarn(w)
+	}
 
 	if !LoadRepo(false) {
 		return
@@ -103,6 +142,9 @@ func HandleInstall(pkg string, isolated bool, dryRun bool) {
 		if isolated {
 			fmt.Println("  - isolated home: " + homeDir)
 		}
+		if summary := opts.Summary(); len(summary) > 0 {
+			fmt.Println("  - run options: " + strings.Join(summary, ", "))
+		}
 		if len(libNames) > 0 {
 			fmt.Println("  - dependencies: " + strings.Join(libNames, ", "))
 		}
@@ -132,13 +174,20 @@ func HandleInstall(pkg string, isolated bool, dryRun bool) {
 
 	newContainer := false
 	if !ContainerExists(contName) {
-		if !CreateContainer(contName, d.Image, homeDir, info.Type, d.InitSystem) {
+		// Only a container about to be created needs its ports free; an
+		// existing one may well be the process holding them.
+		if err := opts.CheckPorts(); err != nil {
+			PrintError(err.Error())
+			return
+		}
+		if !CreateContainer(contName, d.Image, homeDir, info.Type, d.InitSystem, opts) {
 			PrintError(fmt.Sprintf("Failed to create container '%s'", contName))
 			return
 		}
 		newContainer = true
 	} else {
 		PrintInfo(fmt.Sprintf("Reusing existing container '%s'", contName))
+		warnRunOptionsMismatch(contName, opts)
 		if !EnsureContainerRunning(contName) {
 			PrintError(fmt.Sprintf("Failed to start container '%s'", contName))
 			return
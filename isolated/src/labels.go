@@ -0,0 +1,103 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelNamespaceRe is a reverse-DNS prefix: at least two dot-separated
+// DNS labels ("com.example", "io.github.myteam"), each lowercase letters,
+// digits and inner hyphens — the convention OCI annotations and container
+// labels use to keep tools and teams from overwriting each other's keys.
+var labelNamespaceRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+func validateLabelNamespace(ns string) error {
+	if !labelNamespaceRe.MatchString(ns) || len(ns) > 253 {
+		return fmt.Errorf("--label-namespace %q should be a reverse-DNS prefix like com.example", ns)
+	}
+	return nil
+}
+
+// validateLabel checks a --label entry, key=value; the value may be empty
+// but the key may not, nor contain whitespace.
+func validateLabel(label string) error {
+	key, _, ok := strings.Cut(label, "=")
+	if !ok || key == "" || strings.ContainsAny(key, " \t\n") {
+		return fmt.Errorf("--label %q should be key=value", label)
+	}
+	return nil
+}
+
+// namespacedLabel puts ns in front of label's key: "team=platform" under
+// "com.example" becomes "com.example.team=platform". A key already under
+// ns is left as it is, so labels copied from `podman inspect` don't get
+// the prefix twice.
+func namespacedLabel(ns, label string) string {
+	if ns == "" || strings.HasPrefix(label, ns+".") {
+		return label
+	}
+	return ns + "." + label
+}
+
+func buildLabelArgs(ns string, labels []string) []string {
+	var args []string
+	for _, l := range labels {
+		args = append(args, "--label", namespacedLabel(ns, l))
+	}
+	return args
+}
+
+// maxAnnotationValue caps an --annotation value. The OCI spec sets no
+// limit, but everything in it is stored in the container's config and
+// read back on every podman inspect.
+const maxAnnotationValue = 64 << 10
+
+// validateAnnotation checks an --annotation entry, key=value. Any key is
+// accepted, as Kubernetes' own (io.kubernetes.pod.name) and other tools'
+// have to be stored as they are; annotationWarnings flags keys outside
+// the reverse-DNS convention.
+func validateAnnotation(a string) error {
+	key, val, ok := strings.Cut(a, "=")
+	if !ok || key == "" || strings.ContainsAny(key, " \t\n") {
+		return fmt.Errorf("--annotation %q should be key=value", a)
+	}
+	if len(val) > maxAnnotationValue {
+		return fmt.Errorf("--annotation %s: the value is %d bytes, over the %d KB limit", key, len(val), maxAnnotationValue>>10)
+	}
+	return nil
+}
+
+// annotationWarnings lists annotation keys not under a reverse-DNS
+// prefix, which another tool could just as well pick for something else.
+func annotationWarnings(annotations []string) []string {
+	var warnings []string
+	for _, a := range annotations {
+		key, _, _ := strings.Cut(a, "=")
+		if i := strings.LastIndex(key, "."); i < 0 || !labelNamespaceRe.MatchString(key[:i]) {
+			warnings = append(warnings, fmt.Sprintf("--annotation %s: keys are normally under a reverse-DNS prefix (like io.kubernetes.pod.name) so tools don't overwrite each other's", key))
+		}
+	}
+	return warnings
+}
+
+// annotationMatches reports whether annotations include filter, KEY (any
+// value) or KEY=VALUE.
+func annotationMatches(annotations []string, filter string) bool {
+	key, val, exact := strings.Cut(filter, "=")
+	for _, a := range annotations {
+		k, v, _ := strings.Cut(a, "=")
+		if k == key && (!exact || v == val) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildAnnotationArgs(annotations []string) []string {
+	var args []string
+	for _, a := range annotations {
+		args = append(args, "--annotation", a)
+	}
+	return args
+}
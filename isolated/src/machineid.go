@@ -0,0 +1,77 @@
+package src
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Most images ship an empty /etc/machine-id (or none), so journald, D-Bus
+// and anything keyed on the machine ID see every container as the same
+// machine — or as no machine at all. By default each container gets an
+// ID of its own, generated when it's first created and kept in
+// ~/.config/isolator/machine-ids/<container>, so it survives rollbacks
+// and recreation; it's bind-mounted read-only over /etc/machine-id and
+// D-Bus's older /var/lib/dbus/machine-id. --machine-id host shares the
+// host's instead, and none leaves the image's file alone.
+
+// machineIDPaths are where the ID is mounted inside the container.
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+func machineIDFile(cont string) string {
+	return ConfigPath(filepath.Join("machine-ids", cont))
+}
+
+func validateMachineID(mode string) error {
+	switch mode {
+	case "", "host", "none":
+		return nil
+	}
+	return fmt.Errorf("--machine-id %q is not supported (expected 'host' or 'none'; leave unset for one generated per container)", mode)
+}
+
+// ensureMachineID writes cont's machine ID unless it already has one:
+// 32 lowercase hex digits and a newline, the format machine-id(5) wants.
+func ensureMachineID(cont string) error {
+	path := machineIDFile(cont)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("generating a machine ID for %s: %v", cont, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(hex.EncodeToString(id)+"\n"), 0644)
+}
+
+// forgetMachineID removes cont's generated ID once the container is gone.
+func forgetMachineID(cont string) {
+	_ = os.Remove(machineIDFile(cont))
+}
+
+// buildMachineIDArgs mounts the machine ID for mode; ensureMachineID must
+// have run first for the default, generated one.
+func buildMachineIDArgs(cont, mode string) []string {
+	var file string
+	switch mode {
+	case "":
+		file = machineIDFile(cont)
+	case "host":
+		if _, err := os.Stat("/etc/machine-id"); err != nil {
+			return nil
+		}
+		file = "/etc/machine-id"
+	default:
+		return nil
+	}
+	var args []string
+	for _, p := range machineIDPaths {
+		args = append(args, "--volume", file+":"+p+":ro")
+	}
+	return args
+}
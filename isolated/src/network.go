@@ -0,0 +1,254 @@
+package src
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// l2Network is a parsed --network macvlan:IFACE[:MODE] or
+// ipvlan:IFACE[:MODE]: the container gets an interface of its own on the
+// host NIC's network instead of going through podman's NAT, so it is a
+// first-class member of the LAN (multicast, mDNS, inbound connections on
+// any port, no userspace proxy in the path).
+type l2Network struct {
+	Driver string // "macvlan" | "ipvlan"
+	Parent string // host interface, e.g. "eth0"
+	Mode   string // macvlan: bridge|private|vepa|passthru; ipvlan: l2|l3|l3s
+}
+
+var l2Modes = map[string][]string{
+	"macvlan": {"bridge", "private", "vepa", "passthru"},
+	"ipvlan":  {"l2", "l3", "l3s"},
+}
+
+func parseL2Network(spec string) (l2Network, error) {
+	parts := strings.Split(spec, ":")
+	modes, ok := l2Modes[parts[0]]
+	if !ok || len(parts) < 2 || len(parts) > 3 || parts[1] == "" {
+		return l2Network{}, fmt.Errorf("--network %q should be macvlan:IFACE[:MODE] or ipvlan:IFACE[:MODE]", spec)
+	}
+	n := l2Network{Driver: parts[0], Parent: parts[1], Mode: modes[0]}
+	if len(parts) == 3 {
+		if !stringInSlice(parts[2], modes) {
+			return l2Network{}, fmt.Errorf("--network %q: %s mode should be one of %s", spec, n.Driver, strings.Join(modes, ", "))
+		}
+		n.Mode = parts[2]
+	}
+	return n, nil
+}
+
+// podmanName is the podman network Isolator creates for n and reuses for
+// every container asking for the same attachment. Static addressing gets
+// a network of its own: one with a subnet would otherwise hand out
+// addresses itself to DHCP containers, clashing with the LAN's server.
+func (n l2Network) podmanName(static bool) string {
+	name := fmt.Sprintf("isolator-%s-%s-%s", n.Driver, n.Parent, n.Mode)
+	if static {
+		name += "-static"
+	}
+	return name
+}
+
+// validateNetwork checks --network and --ip together: ipvlan interfaces
+// share the parent's MAC address, which DHCP servers can't tell apart, so
+// they need a static --ip; and ports are reachable on a LAN address
+// directly, so --publish has nothing to forward.
+func validateNetwork(network, ip string, publish []string) error {
+	if network == "" {
+		if ip != "" {
+			return fmt.Errorf("--ip needs --network macvlan:IFACE or ipvlan:IFACE")
+		}
+		return nil
+	}
+	n, err := parseL2Network(network)
+	if err != nil {
+		return err
+	}
+	if ip != "" && net.ParseIP(ip).To4() == nil {
+		return fmt.Errorf("--ip %q is not an IPv4 address", ip)
+	}
+	if n.Driver == "ipvlan" && ip == "" {
+		return fmt.Errorf("--network %s needs a static --ip: ipvlan interfaces share %s's MAC address, so DHCP can't tell them apart", network, n.Parent)
+	}
+	if len(publish) > 0 {
+		return fmt.Errorf("--publish has no effect with --network %s: the container has its own address on the LAN, so every port it listens on is reachable there", network)
+	}
+	return nil
+}
+
+// checkNetworkHost fails early on what would otherwise only fail when
+// podman creates the network or starts the container.
+func checkNetworkHost(network, ip string) error {
+	n, err := parseL2Network(network)
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("--network %s needs rootful podman: %s interfaces are created in the host's network namespace, which rootless podman can't touch (run the install with sudo)", network, n.Driver)
+	}
+	if _, err := net.InterfaceByName(n.Parent); err != nil {
+		return fmt.Errorf("--network %s: no network interface named '%s' on this host", network, n.Parent)
+	}
+	if ip != "" {
+		if _, err := hostSubnet(n.Parent, net.ParseIP(ip)); err != nil {
+			return fmt.Errorf("--ip: %v", err)
+		}
+	}
+	return nil
+}
+
+// hostSubnet finds the subnet on iface that ip belongs to, from the
+// addresses the host itself has there.
+func hostSubnet(iface string, ip net.IP) (*net.IPNet, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, _ := ifi.Addrs()
+	for _, a := range addrs {
+		if ipn, ok := a.(*net.IPNet); ok && ipn.Contains(ip) {
+			return &net.IPNet{IP: ipn.IP.Mask(ipn.Mask), Mask: ipn.Mask}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s isn't in any subnet %s has an address in", ip, iface)
+}
+
+// defaultGatewayVia returns the IPv4 default gateway routed through
+// iface, from /proc/net/route, or "" if there's none.
+func defaultGatewayVia(iface string) string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 || fields[0] != iface || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		gw := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(gw, binary.BigEndian.Uint32(b))
+		return gw.String()
+	}
+	return ""
+}
+
+// ensureL2Network creates the podman network for --network/--ip unless
+// it exists already, and returns its name. Without --ip it uses podman's
+// DHCP IPAM, which needs netavark's DHCP proxy
+// (netavark-dhcp-proxy.socket) running.
+func ensureL2Network(network, ip string) (string, error) {
+	n, err := parseL2Network(network)
+	if err != nil {
+		return "", err
+	}
+	name := n.podmanName(ip != "")
+	if exec.Command(podmanBin, "network", "exists", name).Run() == nil {
+		return name, nil
+	}
+	args := []string{"network", "create", "--driver", n.Driver, "--opt", "parent=" + n.Parent, "--opt", "mode=" + n.Mode}
+	if ip == "" {
+		args = append(args, "--ipam-driver", "dhcp")
+	} else {
+		subnet, err := hostSubnet(n.Parent, net.ParseIP(ip))
+		if err != nil {
+			return "", fmt.Errorf("--ip: %v", err)
+		}
+		args = append(args, "--subnet", subnet.String())
+		if gw := defaultGatewayVia(n.Parent); gw != "" && subnet.Contains(net.ParseIP(gw)) {
+			args = append(args, "--gateway", gw)
+		}
+	}
+	args = append(args, name)
+	if out, err := exec.Command(podmanBin, args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("creating podman network %s failed: %s", name, strings.TrimSpace(string(out)))
+	}
+	return name, nil
+}
+
+// Netavark gives a macvlan interface a random MAC address every time the
+// container starts, so a DHCP server hands it a new lease each time — and,
+// with a short pool, a new address. By default (--mac-address auto) the
+// MAC is derived from the container's name instead, which stays the same
+// across restarts, rollbacks and recreation where podman's container ID
+// doesn't. The host's machine ID goes into the hash too, so containers of
+// the same name on two hosts of one LAN don't collide.
+
+// macFromSeed derives a MAC address from seed: the first six bytes of its
+// SHA-256, with the locally administered bit set and the multicast bit
+// cleared, so it can't clash with a vendor-assigned address.
+func macFromSeed(seed string) net.HardwareAddr {
+	sum := sha256.Sum256([]byte(seed))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = (mac[0] | 0x02) &^ 0x01
+	return mac
+}
+
+// autoMAC is cont's --mac-address auto address on this host.
+func autoMAC(cont string) net.HardwareAddr {
+	id, _ := os.ReadFile("/etc/machine-id")
+	return macFromSeed(strings.TrimSpace(string(id)) + "/" + cont)
+}
+
+// validateMACAddress checks --mac-address: auto (the default), random, or
+// a unicast address. Only a macvlan interface has a MAC of its own to set.
+func validateMACAddress(mac, network string) error {
+	if mac == "" {
+		return nil
+	}
+	if n, err := parseL2Network(network); err != nil || n.Driver != "macvlan" {
+		return fmt.Errorf("--mac-address needs --network macvlan:IFACE (ipvlan interfaces share their parent's MAC, and podman's NAT network isn't on the LAN)")
+	}
+	if mac == "auto" || mac == "random" {
+		return nil
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return fmt.Errorf("--mac-address %q should be auto, random or an address like 02:42:ac:11:00:02", mac)
+	}
+	if hw[0]&0x01 != 0 {
+		return fmt.Errorf("--mac-address %s is a multicast address; an interface needs a unicast one", mac)
+	}
+	return nil
+}
+
+// buildMACAddressArgs sets a macvlan container's MAC address; random
+// leaves it to netavark.
+func buildMACAddressArgs(cont, network, mac string) []string {
+	if n, err := parseL2Network(network); err != nil || n.Driver != "macvlan" {
+		return nil
+	}
+	switch mac {
+	case "random":
+		return nil
+	case "", "auto":
+		mac = autoMAC(cont).String()
+	}
+	return []string{"--mac-address", mac}
+}
+
+// buildNetworkArgs attaches the container to the network ensureL2Network
+// made for it.
+func buildNetworkArgs(network, ip string) []string {
+	n, err := parseL2Network(network)
+	if err != nil {
+		return nil
+	}
+	args := []string{"--network", n.podmanName(ip != "")}
+	if ip != "" {
+		args = append(args, "--ip", ip)
+	}
+	return args
+}
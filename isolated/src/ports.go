@@ -0,0 +1,155 @@
+package src
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// portSpec is one parsed --publish:
+//
+//	8080:80                  host port 8080 → container port 80, TCP
+//	127.0.0.1:2222:22        bound to one host address only
+//	5353:5353/udp            UDP instead of TCP
+//	8000-8010:8000-8010      a range (both sides the same length)
+//	[::1]:8443:443/tcp       IPv6 host addresses go in brackets
+//
+// The host port is required: a random one would have to be looked up
+// after every (re)creation, and couldn't be checked for conflicts up front.
+type portSpec struct {
+	HostIP              string
+	HostFirst, HostLast int
+	ContFirst, ContLast int
+	Proto               string // "tcp" or "udp"
+}
+
+func parsePortRange(s string) (int, int, error) {
+	first, last, isRange := strings.Cut(s, "-")
+	a, err := strconv.Atoi(first)
+	if err != nil || a < 1 || a > 65535 {
+		return 0, 0, fmt.Errorf("%q is not a port (1-65535)", first)
+	}
+	if !isRange {
+		return a, a, nil
+	}
+	b, err := strconv.Atoi(last)
+	if err != nil || b < a || b > 65535 {
+		return 0, 0, fmt.Errorf("%q is not a port range (low-high, 1-65535)", s)
+	}
+	return a, b, nil
+}
+
+func parsePortSpec(spec string) (portSpec, error) {
+	p := portSpec{Proto: "tcp"}
+	rest := spec
+	if addr, proto, ok := strings.Cut(spec, "/"); ok {
+		if proto != "tcp" && proto != "udp" {
+			return p, fmt.Errorf("protocol %q should be tcp or udp", proto)
+		}
+		rest, p.Proto = addr, proto
+	}
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]:")
+		if end < 0 {
+			return p, fmt.Errorf("an IPv6 host address should look like [::1]:8080:80")
+		}
+		p.HostIP, rest = rest[1:end], rest[end+2:]
+		if ip := net.ParseIP(p.HostIP); ip == nil || ip.To4() != nil {
+			return p, fmt.Errorf("%q is not an IPv6 address", p.HostIP)
+		}
+	}
+	parts := strings.Split(rest, ":")
+	switch {
+	case len(parts) == 3 && p.HostIP == "":
+		p.HostIP = parts[0]
+		if net.ParseIP(p.HostIP) == nil {
+			return p, fmt.Errorf("%q is not a host IP address", p.HostIP)
+		}
+		parts = parts[1:]
+	case len(parts) != 2:
+		return p, fmt.Errorf("expected [HOST_IP:]HOST_PORT:CONTAINER_PORT[/tcp|udp]")
+	}
+	var err error
+	if p.HostFirst, p.HostLast, err = parsePortRange(parts[0]); err != nil {
+		return p, fmt.Errorf("host %v", err)
+	}
+	if p.ContFirst, p.ContLast, err = parsePortRange(parts[1]); err != nil {
+		return p, fmt.Errorf("container %v", err)
+	}
+	if p.HostLast-p.HostFirst != p.ContLast-p.ContFirst {
+		return p, fmt.Errorf("host and container ranges differ in length")
+	}
+	return p, nil
+}
+
+func portRangeString(first, last int) string {
+	if first == last {
+		return strconv.Itoa(first)
+	}
+	return strconv.Itoa(first) + "-" + strconv.Itoa(last)
+}
+
+// podmanArg renders p for podman run --publish.
+func (p portSpec) podmanArg() string {
+	s := portRangeString(p.HostFirst, p.HostLast) + ":" + portRangeString(p.ContFirst, p.ContLast) + "/" + p.Proto
+	switch {
+	case strings.Contains(p.HostIP, ":"):
+		return "[" + p.HostIP + "]:" + s
+	case p.HostIP != "":
+		return p.HostIP + ":" + s
+	}
+	return s
+}
+
+// checkFree binds every host port p needs and lets go again, so a port
+// some other process (or container) already holds fails the install
+// before the image is pulled, rather than podman failing the create.
+func (p portSpec) checkFree() error {
+	for port := p.HostFirst; port <= p.HostLast; port++ {
+		addr := net.JoinHostPort(p.HostIP, strconv.Itoa(port))
+		var err error
+		if p.Proto == "udp" {
+			var c net.PacketConn
+			if c, err = net.ListenPacket("udp", addr); err == nil {
+				c.Close()
+			}
+		} else {
+			var l net.Listener
+			if l, err = net.Listen("tcp", addr); err == nil {
+				l.Close()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("host port %s/%s is not available: %v", addr, p.Proto, err)
+		}
+	}
+	return nil
+}
+
+// buildPublishArgs turns validated --publish specs into podman flags.
+func buildPublishArgs(specs []string) []string {
+	var args []string
+	for _, spec := range specs {
+		p, err := parsePortSpec(spec)
+		if err != nil {
+			continue
+		}
+		args = append(args, "--publish", p.podmanArg())
+	}
+	return args
+}
+
+// CheckPorts fails if a host port the options publish is already taken.
+func (o RunOptions) CheckPorts() error {
+	for _, spec := range o.Publish {
+		p, err := parsePortSpec(spec)
+		if err != nil {
+			return err
+		}
+		if err := p.checkFree(); err != nil {
+			return fmt.Errorf("--publish %s: %v", spec, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Install profiles are named bundles of [container] options kept in
+// config.hk, so a set of flags used over and over doesn't have to be
+// retyped:
+//
+//	[profiles]
+//	-> hardened
+//	--> cap_drop_all => true
+//	--> cap_add      => [CHOWN, DAC_OVERRIDE, FOWNER, SETUID, SETGID]
+//	-> gaming
+//	--> extends  => hardened
+//	--> timezone => local
+//	--> locale   => host
+//
+// A profile takes the same keys as [container] (plus "extends", naming a
+// profile whose values it starts from). `isolator install --profile NAME`
+// layers it on top of the [container] defaults, and any flag given
+// explicitly still wins over the profile.
+
+// profileExtendsKey is the one key a profile accepts beyond [container]'s.
+const profileExtendsKey = "extends"
+
+// validateProfiles checks the [profiles] section and returns one message
+// per problem: non-map entries, unknown keys (a typo would otherwise
+// silently drop an option), wrongly typed values, and extends chains that
+// name a missing profile or loop.
+func validateProfiles(sec *HkMap) []string {
+	var problems []string
+	schema := configSchema["container"]
+	for _, name := range sec.Keys() {
+		v, _ := sec.Get(name)
+		if v.Kind != HkMapKind {
+			problems = append(problems, fmt.Sprintf("profile '%s' should be a map of [container] keys", name))
+			continue
+		}
+		for _, key := range v.MapVal.Keys() {
+			val, _ := v.MapVal.Get(key)
+			if key == profileExtendsKey {
+				if val.Kind != HkString {
+					problems = append(problems, fmt.Sprintf("profile '%s': extends should name another profile", name))
+				}
+				continue
+			}
+			kind, known := schema[key]
+			if !known {
+				problems = append(problems, fmt.Sprintf("profile '%s': unknown key '%s' (valid: %s)", name, key, strings.Join(profileKeyNames(), ", ")))
+				continue
+			}
+			if want := hkKindForSchema(kind); val.Kind != want {
+				problems = append(problems, fmt.Sprintf("profile '%s': %s should be %s, got %s", name, key, hkKindName(want), hkKindName(val.Kind)))
+			}
+		}
+		if _, err := profileChain(sec, name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// hkKindForSchema maps a configSchema kind to the HkKind a value needs.
+func hkKindForSchema(kind string) HkKind {
+	switch kind {
+	case "bool":
+		return HkBool
+	case "array":
+		return HkArray
+	case "number":
+		return HkNumber
+	default:
+		return HkString
+	}
+}
+
+func profileKeyNames() []string {
+	keys := []string{profileExtendsKey}
+	for k := range configSchema["container"] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys[1:])
+	return keys
+}
+
+// profileChain returns name's profile and every profile it extends,
+// base-most first, so applying them in order lets each override its base.
+func profileChain(sec *HkMap, name string) ([]*HkMap, error) {
+	var chain []*HkMap
+	seen := map[string]bool{}
+	for cur, child := name, ""; cur != ""; {
+		if seen[cur] {
+			return nil, fmt.Errorf("profile '%s': extends chain loops back to '%s'", name, cur)
+		}
+		seen[cur] = true
+		v, ok := sec.Get(cur)
+		if !ok || v.Kind != HkMapKind {
+			if child != "" {
+				return nil, fmt.Errorf("profile '%s' extends unknown profile '%s'", child, cur)
+			}
+			return nil, fmt.Errorf("no profile named '%s' in config.hk", cur)
+		}
+		chain = append([]*HkMap{v.MapVal}, chain...)
+		child, cur = cur, hkGetString(v.MapVal, profileExtendsKey, "")
+	}
+	return chain, nil
+}
+
+// applyContainerKeys overrides the options m actually sets, using the
+// [container] key names, and leaves the rest of o alone.
+func applyContainerKeys(o RunOptions, m *HkMap) RunOptions {
+	o.Timezone = hkGetString(m, "timezone", o.Timezone)
+	o.Locale = hkGetString(m, "locale", o.Locale)
+	o.Runtime = hkGetString(m, "runtime", o.Runtime)
+	o.NoPasswdEntry = !hkGetBool(m, "passwd_entry", !o.NoPasswdEntry)
+	o.PasswdFile = hkGetString(m, "passwd_file", o.PasswdFile)
+	o.GroupFile = hkGetString(m, "group_file", o.GroupFile)
+	o.NoContainerEnv = !hkGetBool(m, "containerenv", !o.NoContainerEnv)
+	o.NoHosts = !hkGetBool(m, "hosts", !o.NoHosts)
+	o.NoResolvConf = !hkGetBool(m, "resolv_conf", !o.NoResolvConf)
+	o.NoImageVolumes = !hkGetBool(m, "image_volumes", !o.NoImageVolumes)
+	o.MachineID = hkGetString(m, "machine_id", o.MachineID)
+	o.SeccompProfile = hkGetString(m, "seccomp_profile", o.SeccompProfile)
+	o.GroupAdd = hkGetString(m, "group_add", o.GroupAdd)
+	o.CapDropAll = hkGetBool(m, "cap_drop_all", o.CapDropAll)
+	if _, ok := m.Get("cap_add"); ok {
+		o.CapAdd = hkGetStrings(m, "cap_add")
+	}
+	o.DeviceInput = hkGetBool(m, "device_input", o.DeviceInput)
+	o.DeviceKVM = hkGetBool(m, "device_kvm", o.DeviceKVM)
+	o.DeviceVideo = hkGetBool(m, "device_video", o.DeviceVideo)
+	o.PrivilegedDevices = hkGetBool(m, "privileged_devices", o.PrivilegedDevices)
+	if _, ok := m.Get("mask_paths"); ok {
+		o.MaskPaths = hkGetStrings(m, "mask_paths")
+	}
+	o.NoMaskPaths = !hkGetBool(m, "default_mask_paths", !o.NoMaskPaths)
+	o.SysFS = hkGetString(m, "sysfs", o.SysFS)
+	o.DeviceFUSE = hkGetBool(m, "device_fuse", o.DeviceFUSE)
+	if _, ok := m.Get("device_cgroup_rules"); ok {
+		o.DeviceCgroupRules = hkGetStrings(m, "device_cgroup_rules")
+	}
+	o.CgroupNS = hkGetString(m, "cgroupns", o.CgroupNS)
+	o.CgroupRW = hkGetBool(m, "cgroup_rw", o.CgroupRW)
+	o.StorageSize = hkGetString(m, "storage_size", o.StorageSize)
+	if _, ok := m.Get("mounts"); ok {
+		o.Mounts = hkGetStrings(m, "mounts")
+	}
+	if _, ok := m.Get("device_read_iops"); ok {
+		o.DeviceReadIOPS = hkGetStrings(m, "device_read_iops")
+	}
+	if _, ok := m.Get("device_write_iops"); ok {
+		o.DeviceWriteIOPS = hkGetStrings(m, "device_write_iops")
+	}
+	o.CPUShares = int(hkGetNumber(m, "cpu_shares", float64(o.CPUShares)))
+	o.CPUWeight = int(hkGetNumber(m, "cpu_weight", float64(o.CPUWeight)))
+	o.MemoryNodes = hkGetString(m, "memory_node_pin", o.MemoryNodes)
+	o.Umask = hkGetString(m, "umask", o.Umask)
+	if _, ok := m.Get("labels"); ok {
+		o.Labels = hkGetStrings(m, "labels")
+	}
+	o.LabelNamespace = hkGetString(m, "label_namespace", o.LabelNamespace)
+	if _, ok := m.Get("annotations"); ok {
+		o.Annotations = hkGetStrings(m, "annotations")
+	}
+	if _, ok := m.Get("ulimits"); ok {
+		o.Ulimits = hkGetStrings(m, "ulimits")
+	}
+	if _, ok := m.Get("cgroup_conf"); ok {
+		o.CgroupConf = hkGetStrings(m, "cgroup_conf")
+	}
+	if _, ok := m.Get("sysctls"); ok {
+		o.Sysctls = hkGetStrings(m, "sysctls")
+	}
+	o.ProcOpts = hkGetString(m, "proc_opts", o.ProcOpts)
+	o.Network = hkGetString(m, "network", o.Network)
+	return o
+}
+
+// loadProfilesSection reads [profiles] from config.hk. A profile with any
+// problem is an error here rather than a warning: asking for it by name
+// and getting half of it would be worse than not installing.
+func loadProfilesSection() (*HkMap, error) {
+	path := configFilePath()
+	if _, err := os.Stat(path); err != nil {
+		return NewHkMap(), nil
+	}
+	doc, err := LoadHKFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config.hk: %v", err)
+	}
+	if err := ResolveInterpolations(doc); err != nil {
+		return nil, fmt.Errorf("config.hk: %v", err)
+	}
+	sec := doc.Section("profiles")
+	if problems := validateProfiles(sec); len(problems) > 0 {
+		return nil, fmt.Errorf("config.hk [profiles]: %s", strings.Join(problems, "; "))
+	}
+	return sec, nil
+}
+
+// ApplyProfile layers the named profile (and whatever it extends) on top
+// of opts.
+func ApplyProfile(opts RunOptions, name string) (RunOptions, error) {
+	sec, err := loadProfilesSection()
+	if err != nil {
+		return opts, err
+	}
+	chain, err := profileChain(sec, name)
+	if err != nil {
+		return opts, err
+	}
+	for _, m := range chain {
+		opts = applyContainerKeys(opts, m)
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, fmt.Errorf("profile '%s': %v", name, err)
+	}
+	return opts, nil
+}
+
+// HandleConfigProfiles lists the profiles defined in config.hk with the
+// options each resolves to on top of the current [container] defaults.
+func HandleConfigProfiles() {
+	sec, err := loadProfilesSection()
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if sec.Len() == 0 {
+		PrintInfo("No profiles defined — add a [profiles] section to " + configFilePath())
+		return
+	}
+	base := RunOptionsFromConfig(LoadConfig())
+	for _, name := range sec.Keys() {
+		opts, err := ApplyProfile(base, name)
+		line := "  " + BoldStyle.Render(name)
+		v, _ := sec.Get(name)
+		if parent := hkGetString(v.MapVal, profileExtendsKey, ""); parent != "" {
+			line += DimStyle.Render(" (extends " + parent + ")")
+		}
+		fmt.Println(line)
+		switch summary := opts.Summary(); {
+		case err != nil:
+			fmt.Println("    " + ErrorStyle.Render(err.Error()))
+		case len(summary) == 0:
+			fmt.Println("    " + DimStyle.Render("(defaults only)"))
+		default:
+			fmt.Println("    " + strings.Join(summary, " "))
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// `isolator system reset` puts Isolator back where `isolator init` left
+// it: every managed container (and its anonymous volumes), the wrappers
+// and launchers pointing into them, their snapshots, the podman networks
+// Isolator created and all its state in ~/.config/isolator except
+// config.hk. Podman's own storage is shared with whatever else the user
+// runs in podman, so it's only ever changed through podman — for a
+// storage root that's beyond repair, `podman system reset` is the tool.
+// Isolated home directories hold user data and are only removed with
+// --homes.
+
+// resetKeep are the config directory entries a reset leaves alone: the
+// user's settings and install profiles.
+var resetKeep = []string{"config.hk"}
+
+// resetConfigDir removes everything in dir but keep and returns the names
+// it removed. os.RemoveAll unlinks a symlink rather than following it, so
+// a link in the config directory never takes anything outside it along.
+func resetConfigDir(dir string, keep []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var removed []string
+	for _, e := range entries {
+		if stringInSlice(e.Name(), keep) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e.Name())
+	}
+	return removed, nil
+}
+
+// isolatorNetworks lists the podman networks ensureL2Network created.
+func isolatorNetworks() []string {
+	out, err := exec.Command(podmanBin, "network", "ls", "--format", "{{.Name}}").Output()
+	if err != nil {
+		return nil
+	}
+	var nets []string
+	for _, n := range strings.Fields(string(out)) {
+		if strings.HasPrefix(n, "isolator-") {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// containerImages returns the images conts were created from.
+func containerImages(conts []string) []string {
+	if len(conts) == 0 {
+		return nil
+	}
+	args := append([]string{"container", "inspect", "--format", "{{.ImageName}}"}, conts...)
+	out, _ := exec.Command(podmanBin, args...).Output()
+	seen := map[string]bool{}
+	var images []string
+	for _, img := range strings.Fields(string(out)) {
+		if !seen[img] {
+			seen[img] = true
+			images = append(images, img)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// confirmReset asks for a typed "yes"; without a terminal to ask on, only
+// --force goes ahead.
+func confirmReset() bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		PrintError("Not resetting: stdin isn't a terminal to confirm on — pass --force to reset anyway")
+		return false
+	}
+	fmt.Print("Type 'yes' to reset Isolator: ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(line) == "yes"
+}
+
+// HandleSystemReset removes everything Isolator has set up, after a typed
+// confirmation unless force is set. keepImages leaves the images the
+// containers were created from in podman's storage, so reinstalling
+// doesn't pull them again; snapshot images go either way.
+func HandleSystemReset(force, keepImages, homes bool) {
+	installed, err := LoadInstalled()
+	if err != nil {
+		PrintWarn("Couldn't read installed packages, so their wrappers and launchers stay: " + err.Error())
+	}
+	conts := GetOurContainers()
+	var images []string
+	if !keepImages {
+		images = containerImages(conts)
+	}
+	for _, r := range loadSnapshots() {
+		if !stringInSlice(r.Image, images) {
+			images = append(images, r.Image)
+		}
+	}
+	nets := isolatorNetworks()
+	configRoot := filepath.Join(os.Getenv("HOME"), configDir)
+	homesRoot := filepath.Join(os.Getenv("HOME"), homesDir)
+
+	list := func(what string, names []string) {
+		line := fmt.Sprintf("  - %d %s", len(names), what)
+		if len(names) > 0 {
+			line += ": " + strings.Join(names, ", ")
+		}
+		fmt.Println(line)
+	}
+	PrintWarn("This removes everything Isolator has set up:")
+	list("container(s), with their volumes", conts)
+	fmt.Printf("  - %d package wrapper(s) and launcher(s)\n", len(installed))
+	list("image(s)", images)
+	list("network(s)", nets)
+	fmt.Printf("  - Isolator's state in %s (config.hk is kept)\n", configRoot)
+	if homes {
+		fmt.Printf("  - every isolated home directory in %s\n", homesRoot)
+	} else {
+		fmt.Println(DimStyle.Render("  Isolated homes in " + homesRoot + " are kept (--homes removes them too)"))
+	}
+	if !force && !confirmReset() {
+		PrintInfo("Reset cancelled; nothing was changed")
+		return
+	}
+
+	var failed int
+	for _, ip := range installed {
+		RemoveWrapper(ip.Pkg)
+		RemoveDesktopEntry(ip.Pkg)
+	}
+	removedConts := 0
+	for _, c := range conts {
+		PrintStep("Removing " + c + "...")
+		if ExecCommand(podmanBin, []string{"rm", "--force", "--volumes", c}) {
+			removedConts++
+		} else {
+			failed++
+		}
+	}
+	removedImages := 0
+	for _, img := range images {
+		// Without --force, podman refuses to remove an image another
+		// (non-Isolator) container still uses, which is what's wanted.
+		if exec.Command(podmanBin, "rmi", img).Run() == nil {
+			removedImages++
+		} else {
+			PrintWarn("Kept image " + img + ": podman couldn't remove it (is another container using it?)")
+		}
+	}
+	removedNets := 0
+	for _, n := range nets {
+		if exec.Command(podmanBin, "network", "rm", n).Run() == nil {
+			removedNets++
+		} else {
+			failed++
+			PrintWarn("Couldn't remove network " + n)
+		}
+	}
+	state, err := resetConfigDir(configRoot, resetKeep)
+	if err != nil {
+		failed++
+		PrintError("Resetting " + configRoot + ": " + err.Error())
+	}
+	removedHomes := false
+	if homes {
+		if err := os.RemoveAll(homesRoot); err != nil {
+			failed++
+			PrintError("Removing " + homesRoot + ": " + err.Error())
+		} else {
+			removedHomes = true
+		}
+	}
+	if err := EnsureConfigDir(); err != nil {
+		failed++
+		PrintError("Failed to recreate " + configRoot + ": " + err.Error())
+	}
+
+	summary := fmt.Sprintf("Removed %d container(s), %d wrapper(s), %d image(s), %d network(s) and %d state file(s)", removedConts, len(installed), removedImages, removedNets, len(state))
+	if removedHomes {
+		summary += ", plus every isolated home"
+	}
+	if failed > 0 {
+		PrintWarn(summary + fmt.Sprintf(" — %d step(s) failed, see above", failed))
+		return
+	}
+	PrintSuccess(summary)
+}
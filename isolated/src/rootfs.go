@@ -0,0 +1,54 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --rootfs creates a container from a root filesystem tree already on the
+// host (debootstrap output, an unpacked SDK) instead of the distro's
+// image, so nothing is pulled or imported into podman's storage. Every
+// other option applies as it would to an image. Podman runs the tree in
+// place, so the container's writes land in it; with podman's :O suffix
+// they go to an overlay upper layer in podman's storage instead and the
+// tree is left as it was.
+
+// parseRootFS splits a --rootfs spec into the host directory and whether
+// it's overlaid.
+func parseRootFS(spec string) (dir string, overlay bool) {
+	if d, ok := strings.CutSuffix(spec, ":O"); ok {
+		return d, true
+	}
+	return spec, false
+}
+
+// validateRootFS checks that --rootfs names an absolute directory that
+// looks like a root filesystem: one with /bin or /usr, without which the
+// container's /bin/sh couldn't start anyway.
+func validateRootFS(spec string) error {
+	dir, _ := parseRootFS(spec)
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("--rootfs %q should be an absolute path on the host", dir)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return fmt.Errorf("--rootfs %s is not a directory", dir)
+	}
+	for _, d := range []string{"bin", "usr"} {
+		if fi, err := os.Stat(filepath.Join(dir, d)); err == nil && fi.IsDir() {
+			return nil
+		}
+	}
+	return fmt.Errorf("--rootfs %s doesn't look like a root filesystem (it has neither bin/ nor usr/)", dir)
+}
+
+// containerSource is what a container is created from: its rootfs tree
+// if it has one, otherwise image. It's also what ISOLATOR_IMAGE reports.
+func containerSource(image string, opts RunOptions) string {
+	if opts.RootFS == "" {
+		return image
+	}
+	dir, _ := parseRootFS(opts.RootFS)
+	return dir
+}
@@ -0,0 +1,723 @@
+package src
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunOptionsValidate(t *testing.T) {
+	valid := []RunOptions{
+		{},
+		{Timezone: "local"},
+		{Timezone: "UTC"},
+		{Locale: "host"},
+		{CapDropAll: true, CapAdd: []string{"net_bind_service", "CAP_CHOWN"}},
+		{MaskPaths: []string{"/proc/cpuinfo", "/sys/class/dmi"}},
+		{USB: []string{"0483:DF11", "1.7"}},
+		{DeviceCgroupRules: []string{"c 189:* rwm", "b 8:0 r", "a *:* m"}},
+		{CgroupNS: "private"},
+		{CgroupNS: "host"},
+		{CgroupNS: "auto"},
+		{StorageSize: "20G"},
+		{StorageSize: "512mb"},
+		{PID: "container:isolator-arch-firefox"},
+		{CPUShares: 512},
+		{CPUWeight: 10000},
+		{Labels: []string{"team=platform", "note="}, LabelNamespace: "com.example"},
+		{LabelNamespace: "io.github.my-team"},
+		{Ulimits: []string{"core=unlimited", "nofile=4096:65536", "stack=-1"}},
+		{ProcOpts: "hidepid=2"},
+		{ProcOpts: "hidepid=invisible,gid=10,subset=pid"},
+		{Network: "macvlan:eth0"},
+		{Network: "macvlan:eth0:vepa", IP: "192.168.1.50"},
+		{Network: "ipvlan:enp3s0:l3", IP: "10.0.0.7"},
+		{CgroupConf: []string{"cpu.idle=1", "memory.high=4G", "io.weight=default 200"}},
+		{CgroupRW: true, CgroupNS: "private"},
+		{MachineID: "host"},
+		{MachineID: "none"},
+		{SeccompProfile: "unconfined"},
+		{GroupAdd: "keep-groups"},
+		{Sysctls: []string{"net.core.somaxconn=4096", "net.ipv4.conf.eth0.rp_filter=2", "kernel.sem=250 32000 100 128", "fs.mqueue.msg_max=100"}},
+		{Annotations: []string{"io.kubernetes.container.name=web", "io.kubernetes.pod.name=", "plain=ok"}},
+		{Network: "macvlan:eth0", MACAddress: "random"},
+		{Network: "macvlan:eth0", MACAddress: "02:42:ac:11:00:02"},
+		{SysFS: "masked"},
+		{SysFS: "read-only"},
+		{SysFS: "unconfined"},
+		{MemoryNodes: "0"},
+		{MemoryNodes: "0,2-3"},
+		{Umask: "0027"},
+		{Umask: "077"},
+	}
+	for _, o := range valid {
+		if err := o.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got error: %v", o, err)
+		}
+	}
+
+	invalid := []RunOptions{
+		{Timezone: "Europe/../../etc/passwd"},
+		{Timezone: "Not/AZone"},
+		{Timezone: "UTC; rm -rf /"},
+		{Locale: "fr_FR.UTF-8"},
+		{CapAdd: []string{"CAP_NET_RAWW"}},
+		{MaskPaths: []string{"proc/kcore"}},
+		{MaskPaths: []string{"/proc/../etc/shadow"}},
+		{MaskPaths: []string{"/a:/b"}},
+		{USB: []string{"0483"}},
+		{USB: []string{"/dev/bus/usb/001/007"}},
+		{DeviceCgroupRules: []string{"c 189 rwm"}},
+		{DeviceCgroupRules: []string{"c 189:* rwx"}},
+		{DeviceCgroupRules: []string{"c 189:*  rwm"}},
+		{CgroupNS: "container:abc"},
+		{StorageSize: "0"},
+		{StorageSize: "20 GB"},
+		{StorageSize: "-1G"},
+		{PID: "host"},
+		{PID: "container:"},
+		{PID: "container:-x"},
+		{CPUShares: 1},
+		{CPUWeight: 10001},
+		{CPUShares: 1024, CPUWeight: 100},
+		{Labels: []string{"team"}},
+		{Labels: []string{"=platform"}},
+		{LabelNamespace: "example"},
+		{LabelNamespace: "Com.Example"},
+		{LabelNamespace: "com.-example"},
+		{Ulimits: []string{"files=1024"}},
+		{Ulimits: []string{"nofile=4096:1024"}},
+		{Ulimits: []string{"nofile=unlimited:1024"}},
+		{Ulimits: []string{"core"}},
+		{ProcOpts: "hidepid=3"},
+		{ProcOpts: "subset=sys"},
+		{ProcOpts: "gid=wheel"},
+		{ProcOpts: "nosuid"},
+		{Network: "bridge"},
+		{Network: "macvlan"},
+		{Network: "macvlan:eth0:l2"},
+		{Network: "ipvlan:eth0"},
+		{Network: "macvlan:eth0", IP: "fe80::1"},
+		{Network: "macvlan:eth0", Publish: []string{"8080:80"}},
+		{IP: "192.168.1.50"},
+		{CgroupConf: []string{"../cpu.idle=1"}},
+		{CgroupConf: []string{"cpu/idle=1"}},
+		{CgroupConf: []string{"cpu.idle"}},
+		{CgroupConf: []string{"cpu.idle=1", "cpu.idle=0"}},
+		{CgroupConf: []string{"cpu.weight=50"}, CPUWeight: 200},
+		{CgroupConf: []string{"cpu.weight=500"}, CPUShares: 512},
+		{CgroupRW: true, CgroupNS: "host"},
+		{MachineID: "random"},
+		{SeccompProfile: "seccomp.json"},
+		{SeccompProfile: "/nonexistent/seccomp.json"},
+		{GroupAdd: "docker"},
+		{RootFS: "srv/trees/bookworm"},
+		{RootFS: "/nonexistent/tree:O"},
+		{Sysctls: []string{"vm.swappiness=10"}},
+		{Sysctls: []string{"kernel.semxyz=1"}},
+		{Sysctls: []string{"net/core/somaxconn=4096"}},
+		{Sysctls: []string{"net.core.somaxconn"}},
+		{Sysctls: []string{"net.core.somaxconn=1", "net.core.somaxconn=2"}},
+		{Annotations: []string{"io.kubernetes.pod.name"}},
+		{Annotations: []string{"=mypod"}},
+		{Annotations: []string{"io.example.blob=" + strings.Repeat("x", 64<<10+1)}},
+		{MACAddress: "auto"},
+		{Network: "ipvlan:eth0", IP: "192.168.1.50", MACAddress: "02:42:ac:11:00:02"},
+		{Network: "macvlan:eth0", MACAddress: "01:00:5e:00:00:01"},
+		{Network: "macvlan:eth0", MACAddress: "02:42:ac"},
+		{SysFS: "rw"},
+		{SysFS: "unmasked"},
+		{MemoryNodes: "0-"},
+		{MemoryNodes: "3-1"},
+		{MemoryNodes: "node0"},
+		{MemoryNodes: "0-9999999999"},
+		{MemoryNodes: "1024"},
+		{Umask: "0028"},
+		{Umask: "01777"},
+	}
+	for _, o := range invalid {
+		if err := o.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected, but it passed validation", o)
+		}
+	}
+}
+
+func TestCPUWeightScales(t *testing.T) {
+	for _, c := range []struct{ weight, shares int }{{1, 2}, {100, 2598}, {10000, 262144}} {
+		if got := cpuSharesFromWeight(c.weight); got != c.shares {
+			t.Errorf("cpuSharesFromWeight(%d) = %d, want %d", c.weight, got, c.shares)
+		}
+	}
+}
+
+func TestBuildTimezoneArgs(t *testing.T) {
+	if args := buildTimezoneArgs(""); len(args) != 0 {
+		t.Fatalf("expected no args for the default (UTC) timezone, got %v", args)
+	}
+	args := buildTimezoneArgs("UTC")
+	want := []string{"--tz", "UTC", "--env", "TZ=UTC"}
+	if len(args) != len(want) {
+		t.Fatalf("expected %v, got %v", want, args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, args)
+		}
+	}
+}
+
+func TestContainerOptionsRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	unrecorded := RunOptions{MachineID: "none", Unrecorded: true}
+	if got, ok := LoadContainerOptions("debian-testing"); ok || !got.Equal(unrecorded) {
+		t.Fatalf("expected %+v for an unrecorded container, got %+v (ok=%v)", unrecorded, got, ok)
+	}
+	opts := RunOptions{Timezone: "local", Locale: "host", NoPasswdEntry: true, CapDropAll: true, CapAdd: []string{"NET_RAW"}}
+	if err := SaveContainerOptions("debian-testing", opts); err != nil {
+		t.Fatalf("SaveContainerOptions failed: %v", err)
+	}
+	if got, ok := LoadContainerOptions("debian-testing"); !ok || !got.Equal(opts) {
+		t.Fatalf("expected %+v after reload, got %+v (ok=%v)", opts, got, ok)
+	}
+	ForgetContainerOptions("debian-testing")
+	if got, ok := LoadContainerOptions("debian-testing"); ok || !got.Equal(unrecorded) {
+		t.Fatalf("expected options to be gone after ForgetContainerOptions, got %+v", got)
+	}
+}
+
+func TestUnrecordedOptionsSkipNewDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	opts, _ := LoadContainerOptions("created-before-records")
+	args := strings.Join(getPodmanRunArgs("created-before-records", "img", "", "app", "", opts), " ")
+	for _, flag := range []string{"--passwd-entry", "--group-entry", "--passwd=false", "--ulimit", "machine-id"} {
+		if strings.Contains(args, flag) {
+			t.Errorf("recreating an unrecorded container added %s: %s", flag, args)
+		}
+	}
+	if got := strings.Join(BuildRunOptionArgs(RunOptions{}), " "); !strings.Contains(got, "--ulimit core=0") || !strings.Contains(got, "--passwd-entry") {
+		t.Errorf("recorded zero options lost their defaults: %s", got)
+	}
+}
+
+func TestBuildCapabilityArgs(t *testing.T) {
+	args := buildCapabilityArgs(true, []string{"net_raw", "CAP_SYS_PTRACE"})
+	want := []string{"--cap-drop=all", "--cap-add=CAP_NET_RAW", "--cap-add=CAP_SYS_PTRACE"}
+	if len(args) != len(want) {
+		t.Fatalf("expected %v, got %v", want, args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, args)
+		}
+	}
+	if len(capabilityNumbers) != 41 {
+		t.Errorf("expected all 41 capabilities from linux/capability.h, got %d", len(capabilityNumbers))
+	}
+
+	// The range itself depends on the host's ids, so it's checked through
+	// pingGroupRange with known ones below.
+	if got := buildPingArgs([]string{"net_raw"}, nil); len(got) != 2 || got[0] != "--sysctl" || !strings.HasPrefix(got[1], "net.ipv4.ping_group_range=") {
+		t.Errorf("NET_RAW: got %q, want the ping_group_range sysctl", got)
+	}
+	sub := idRange{Start: 100000, Count: 65536}
+	for _, c := range []struct {
+		rootful bool
+		gid     int
+		sub     *idRange
+		want    string
+	}{
+		{true, 0, nil, "net.ipv4.ping_group_range=0 2147483647"},
+		{false, 1000, &sub, "net.ipv4.ping_group_range=0 65536"},
+		{false, 70000, &sub, "net.ipv4.ping_group_range=0 70000"},
+		{false, 1000, nil, "net.ipv4.ping_group_range=1000 1000"},
+	} {
+		if got := pingGroupRange(c.rootful, c.gid, c.sub); got != c.want {
+			t.Errorf("pingGroupRange(%v, %d, %v) = %q, want %q", c.rootful, c.gid, c.sub, got, c.want)
+		}
+		if !c.rootful && c.sub != nil {
+			m := keepIDMap{c.gid, *c.sub}
+			if _, ok := m.host(m.maxMapped()); !ok {
+				t.Errorf("rootless upper bound %d isn't mapped by %+v", m.maxMapped(), m)
+			}
+		}
+	}
+	if got := buildPingArgs([]string{"NET_RAW"}, []string{"net.ipv4.ping_group_range=0 0"}); got != nil {
+		t.Errorf("an explicit ping_group_range should win, got %q", got)
+	}
+	if got := buildPingArgs([]string{"SYS_PTRACE"}, nil); got != nil {
+		t.Errorf("no NET_RAW: got %q, want nothing", got)
+	}
+}
+
+func TestBuildMaskArgs(t *testing.T) {
+	if args := buildMaskArgs(nil, false); len(args) != 0 {
+		t.Fatalf("expected podman's default masks to need no args, got %v", args)
+	}
+	args := buildMaskArgs([]string{"/proc/cpuinfo", "/sys/class/dmi"}, true)
+	want := []string{"--security-opt", "unmask=ALL", "--security-opt", "mask=/proc/cpuinfo:/sys/class/dmi"}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, args)
+	}
+}
+
+func TestResolveUSB(t *testing.T) {
+	root := t.TempDir()
+	write := func(dir string, files map[string]string) {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(root, dir, name), []byte(content+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write("1-2", map[string]string{"idVendor": "0483", "idProduct": "DF11", "busnum": "1", "devnum": "7", "product": "STM32 BOOTLOADER"})
+	write("1-2:1.0", map[string]string{"bInterfaceClass": "fe"})
+	write("usb2", map[string]string{"idVendor": "1d6b", "idProduct": "0003", "busnum": "2", "devnum": "1"})
+
+	for _, spec := range []string{"0483:df11", "1.7"} {
+		devices, err := resolveUSB(root, spec)
+		if err != nil {
+			t.Fatalf("resolveUSB(%q) failed: %v", spec, err)
+		}
+		if len(devices) != 1 || devices[0].node() != "/dev/bus/usb/001/007" {
+			t.Fatalf("resolveUSB(%q) = %+v, want the device at /dev/bus/usb/001/007", spec, devices)
+		}
+	}
+
+	_, err := resolveUSB(root, "dead:beef")
+	if err == nil || !strings.Contains(err.Error(), "available devices are") || !strings.Contains(err.Error(), "1d6b:0003") {
+		t.Fatalf("expected a not-found error listing attached devices, got %v", err)
+	}
+}
+
+func TestBuildCgroupRuleArgs(t *testing.T) {
+	root := t.TempDir()
+	for dev, name := range map[string]string{"char/189:7": "bus/usb/001/007", "char/4:1": "tty1", "block/189:0": "sdz"} {
+		if err := os.MkdirAll(filepath.Join(root, dev), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dev, "uevent"), []byte("MAJOR=x\nDEVNAME="+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := strings.Join(buildCgroupRuleArgs(root, "c 189:* rwm"), " ")
+	if !strings.HasPrefix(got, "--device /dev/bus/usb/001/007:/dev/bus/usb/001/007:rwm") {
+		t.Errorf("expected the matching char device to be passed through, got %q", got)
+	}
+	if strings.Contains(got, "tty1") || strings.Contains(got, "sdz") {
+		t.Errorf("expected only char devices with major 189, got %q", got)
+	}
+
+	deduped := dedupeDeviceArgs([]string{"--device", "/dev/fuse", "--device", "/dev/fuse:/dev/fuse:rwm", "--device-cgroup-rule", "c 10:229 rwm"})
+	if want := "--device /dev/fuse --device-cgroup-rule c 10:229 rwm"; strings.Join(deduped, " ") != want {
+		t.Errorf("dedupeDeviceArgs = %q, want %q", strings.Join(deduped, " "), want)
+	}
+
+	nested := dedupeDeviceArgs([]string{"--device", "/dev/dri:/dev/dri", "--device", "/dev/dri/card0", "--device", "/dev/driver"})
+	if want := "--device /dev/dri:/dev/dri --device /dev/driver"; strings.Join(nested, " ") != want {
+		t.Errorf("dedupeDeviceArgs = %q, want %q", strings.Join(nested, " "), want)
+	}
+
+	hw := buildPrivilegedDeviceArgs([]string{"/dev/nul*", filepath.Join(t.TempDir(), "*")})
+	if len(hw) < 2 || hw[0] != "--device" || hw[1] != "/dev/null" {
+		t.Errorf("buildPrivilegedDeviceArgs = %q; want /dev/null and nothing from an empty directory", hw)
+	}
+	if os.Geteuid() == 0 && (len(hw) != 4 || hw[3] != "c 1:3 rwm") {
+		t.Errorf("buildPrivilegedDeviceArgs as root = %q; want a c 1:3 rule for /dev/null", hw)
+	}
+
+	groups := dedupeGroupAddArgs([]string{"--group-add", "keep-groups", "--device", "/dev/dri:/dev/dri", "--group-add", "keep-groups"})
+	if want := "--group-add keep-groups --device /dev/dri:/dev/dri"; strings.Join(groups, " ") != want {
+		t.Errorf("dedupeGroupAddArgs = %q, want %q", strings.Join(groups, " "), want)
+	}
+}
+
+func TestParseMountSpec(t *testing.T) {
+	src := t.TempDir()
+	cases := map[string]string{
+		"type=bind,source=" + src + ",target=/data,readonly":                    "type=bind,source=" + src + ",target=/data,readonly=true",
+		"type=bind,src=" + src + ",dst=/data,bind-propagation=rslave,selinux=Z": "type=bind,source=" + src + ",target=/data,bind-propagation=rslave,relabel=private",
+		"type=volume,source=pgdata,target=/var/lib/postgresql,ro=false":         "type=volume,source=pgdata,target=/var/lib/postgresql",
+	}
+	for spec, want := range cases {
+		m, err := parseMountSpec(spec)
+		if err != nil {
+			t.Errorf("parseMountSpec(%q) failed: %v", spec, err)
+			continue
+		}
+		if got := m.podmanArg(); got != want {
+			t.Errorf("parseMountSpec(%q).podmanArg() = %q, want %q", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{
+		"source=" + src + ",target=/data",
+		"type=tmpfs,target=/data",
+		"type=bind,source=" + src + "/missing,target=/data",
+		"type=bind,source=relative,target=/data",
+		"type=bind,source=" + src + ",target=data",
+		"type=bind,source=" + src + ",target=/data,bind-propagation=weird",
+		"type=bind,source=" + src + ",target=/data,selinux=x",
+		"type=volume,source=pgdata,target=/data,selinux=z",
+		"type=volume,source=../etc,target=/data",
+		"type=bind,source=" + src + ",target=/data,exec",
+	} {
+		if _, err := parseMountSpec(spec); err == nil {
+			t.Errorf("expected parseMountSpec(%q) to fail", spec)
+		}
+	}
+}
+
+func TestParsePortSpec(t *testing.T) {
+	cases := map[string]string{
+		"8080:80":             "8080:80/tcp",
+		"127.0.0.1:2222:22":   "127.0.0.1:2222:22/tcp",
+		"5353:5353/udp":       "5353:5353/udp",
+		"8000-8010:9000-9010": "8000-8010:9000-9010/tcp",
+		"[::1]:8443:443/tcp":  "[::1]:8443:443/tcp",
+	}
+	for spec, want := range cases {
+		p, err := parsePortSpec(spec)
+		if err != nil {
+			t.Errorf("parsePortSpec(%q) failed: %v", spec, err)
+			continue
+		}
+		if got := p.podmanArg(); got != want {
+			t.Errorf("parsePortSpec(%q).podmanArg() = %q, want %q", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{"80", "0:80", "70000:80", "8000-8010:8000-8005", "8010-8000:8010-8000", "8080:80/sctp", "localhost:8080:80", "[127.0.0.1]:8080:80", "::1:8080:80"} {
+		if _, err := parsePortSpec(spec); err == nil {
+			t.Errorf("expected parsePortSpec(%q) to fail", spec)
+		}
+	}
+}
+
+func TestCheckPortsDetectsConflict(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("can't listen on loopback:", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	taken := RunOptions{Publish: []string{fmt.Sprintf("127.0.0.1:%d:80", port)}}
+	if err := taken.CheckPorts(); err == nil {
+		t.Fatalf("expected port %d, held by the test, to be reported as taken", port)
+	}
+	udp := RunOptions{Publish: []string{fmt.Sprintf("127.0.0.1:%d:80/udp", port)}}
+	if err := udp.CheckPorts(); err != nil {
+		t.Fatalf("expected UDP port %d to be free while only TCP is held, got %v", port, err)
+	}
+}
+
+func TestParseIOPSLimit(t *testing.T) {
+	for _, spec := range []string{"/dev/nvme0n1", "1000", "/dev/nvme0n1:0", "/dev/nvme0n1:-5", "/dev/nvme0n1:fast", "/dev/null:1000"} {
+		if _, _, err := parseIOPSLimit(spec); err == nil {
+			t.Errorf("expected parseIOPSLimit(%q) to fail", spec)
+		}
+	}
+
+	var block string
+	for _, pattern := range []string{"/dev/loop*", "/dev/sd*", "/dev/vd*", "/dev/nvme*n*"} {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && fi.Mode()&os.ModeDevice != 0 && fi.Mode()&os.ModeCharDevice == 0 {
+				block = m
+				break
+			}
+		}
+		if block != "" {
+			break
+		}
+	}
+	if block == "" {
+		t.Skip("no block device to test against")
+	}
+	path, n, err := parseIOPSLimit(block + ":1000")
+	if err != nil || path != block || n != 1000 {
+		t.Fatalf("parseIOPSLimit(%q) = %q, %d, %v", block+":1000", path, n, err)
+	}
+	if got := strings.Join(buildIOPSArgs([]string{block + ":1000"}, []string{block + ":500"}), " "); got != "--device-read-iops "+block+":1000 --device-write-iops "+block+":500" {
+		t.Errorf("buildIOPSArgs = %q", got)
+	}
+}
+
+func TestValidateUserDBFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	passwd := write("passwd", "# central users\nroot:x:0:0:root:/root:/bin/sh\nalice:x:1000:1000:Alice:/home/alice:/bin/bash\n")
+	group := write("group", "root:x:0:\nstaff:x:50:alice,bob\n")
+	if err := validateUserDBFile(passwd, 7); err != nil {
+		t.Errorf("expected a valid passwd file, got %v", err)
+	}
+	if err := validateUserDBFile(group, 4); err != nil {
+		t.Errorf("expected a valid group file, got %v", err)
+	}
+	for _, bad := range []string{
+		write("short", "alice:x:1000:1000\n"),
+		write("baduid", "alice:x:abc:1000:Alice:/home/alice:/bin/sh\n"),
+		write("empty", "# nothing\n"),
+		"relative/passwd",
+		filepath.Join(dir, "missing"),
+	} {
+		if err := validateUserDBFile(bad, 7); err == nil {
+			t.Errorf("expected %s to be rejected as a passwd file", bad)
+		}
+	}
+	if err := validateUserDBFile(passwd, 4); err == nil {
+		t.Error("expected a passwd file to be rejected as a group file")
+	}
+
+	args := strings.Join(BuildRunOptionArgs(RunOptions{PasswdFile: passwd}), " ")
+	if !strings.Contains(args, passwd+":/etc/passwd:ro") || strings.Contains(args, "--passwd-entry") || !strings.Contains(args, "--group-entry") {
+		t.Errorf("expected only the passwd file mounted and the group entry kept, got %s", args)
+	}
+}
+
+func TestCheckNodesOnline(t *testing.T) {
+	root := t.TempDir()
+	if err := checkNodesOnline(root, "0"); err == nil {
+		t.Error("no online file: expected an error")
+	}
+	if err := os.WriteFile(filepath.Join(root, "online"), []byte("0-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for list, ok := range map[string]bool{"0": true, "0-1": true, "1,0": true, "2": false, "0-3": false, "0-1023": false} {
+		if err := checkNodesOnline(root, list); (err == nil) != ok {
+			t.Errorf("checkNodesOnline(%q) = %v, want ok=%v", list, err, ok)
+		}
+	}
+	online := []nodeRange{{4, 5}, {0, 1}, {2, 2}}
+	for r, ok := range map[nodeRange]bool{{0, 2}: true, {1, 2}: true, {0, 5}: false, {4, 5}: true, {3, 3}: false, {6, 6}: false} {
+		if got := rangeOnline(r, online); got != ok {
+			t.Errorf("rangeOnline(%v) = %v, want %v", r, got, ok)
+		}
+	}
+}
+
+func TestNamespacedLabel(t *testing.T) {
+	cases := []struct{ ns, label, want string }{
+		{"", "team=platform", "team=platform"},
+		{"com.example", "team=platform", "com.example.team=platform"},
+		{"com.example", "com.example.team=platform", "com.example.team=platform"},
+		{"com.example", "com.examples=x", "com.example.com.examples=x"},
+	}
+	for _, c := range cases {
+		if got := namespacedLabel(c.ns, c.label); got != c.want {
+			t.Errorf("namespacedLabel(%q, %q) = %q, want %q", c.ns, c.label, got, c.want)
+		}
+	}
+}
+
+func TestEffectiveUlimits(t *testing.T) {
+	got := effectiveUlimits(nil)
+	if len(got) != 2 || got[0] != "core=0" || !strings.HasPrefix(got[1], "nofile=") {
+		t.Errorf("defaults = %v, want core=0 and a nofile limit", got)
+	}
+	got = effectiveUlimits([]string{"core=unlimited", "nofile=4096:8192"})
+	if strings.Join(got, " ") != "core=unlimited nofile=4096:8192" {
+		t.Errorf("overrides = %v, want them kept as given with no defaults added", got)
+	}
+	args := strings.Join(buildUlimitArgs([]string{"core=unlimited"}), " ")
+	if !strings.Contains(args, "--ulimit core=-1") {
+		t.Errorf("buildUlimitArgs = %q, want unlimited passed as -1", args)
+	}
+}
+
+func TestMachineIDStable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := ensureMachineID("debian-testing"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(machineIDFile("debian-testing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 33 || strings.Trim(string(first[:32]), "0123456789abcdef") != "" || first[32] != '\n' {
+		t.Errorf("machine ID %q isn't 32 lowercase hex digits and a newline", first)
+	}
+	if err := ensureMachineID("debian-testing"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(machineIDFile("debian-testing")); string(again) != string(first) {
+		t.Errorf("machine ID changed from %q to %q", first, again)
+	}
+	ForgetContainerOptions("debian-testing")
+	if _, err := os.Stat(machineIDFile("debian-testing")); err == nil {
+		t.Error("machine ID file left behind after ForgetContainerOptions")
+	}
+}
+
+func TestValidateSeccompProfile(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		profile string
+		ok      bool
+	}{
+		"strict":     {`{"defaultAction":"SCMP_ACT_ERRNO","syscalls":[{"names":["read","write","exit_group"],"action":"SCMP_ACT_ALLOW"}]}`, true},
+		"single":     {`{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"name":"ptrace","action":"SCMP_ACT_ERRNO"}]}`, true},
+		"bad-action": {`{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"names":["ptrace"],"action":"DENY"}]}`, false},
+		"no-default": {`{"syscalls":[]}`, false},
+		"no-names":   {`{"defaultAction":"SCMP_ACT_ALLOW","syscalls":[{"action":"SCMP_ACT_ERRNO"}]}`, false},
+		"not-json":   {`defaultAction: SCMP_ACT_ALLOW`, false},
+	} {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(tc.profile), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateSeccompProfile(path); (err == nil) != tc.ok {
+			t.Errorf("%s: validateSeccompProfile() = %v, want ok=%v", name, err, tc.ok)
+		}
+	}
+}
+
+func TestValidateRootFS(t *testing.T) {
+	tree := t.TempDir()
+	if err := validateRootFS(tree); err == nil {
+		t.Error("validateRootFS accepted a directory with neither bin/ nor usr/")
+	}
+	if err := os.Mkdir(filepath.Join(tree, "usr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range []string{tree, tree + ":O"} {
+		if err := validateRootFS(spec); err != nil {
+			t.Errorf("validateRootFS(%q) = %v", spec, err)
+		}
+	}
+	if got := containerSource("debian:bookworm", RunOptions{RootFS: tree + ":O"}); got != tree {
+		t.Errorf("containerSource = %q, want %q", got, tree)
+	}
+}
+
+func TestAnnotationFilters(t *testing.T) {
+	have := []string{"io.kubernetes.pod.name=mypod", "io.kubernetes.container.name=web"}
+	for filter, want := range map[string]bool{
+		"io.kubernetes.pod.name":           true,
+		"io.kubernetes.pod.name=mypod":     true,
+		"io.kubernetes.pod.name=otherpod":  false,
+		"io.kubernetes.pod.namespace":      false,
+		"io.kubernetes.container.name=web": true,
+	} {
+		if got := annotationMatches(have, filter); got != want {
+			t.Errorf("annotationMatches(%q) = %v, want %v", filter, got, want)
+		}
+	}
+	if _, err := parseStatusFilters([]string{"label=team=platform"}); err == nil {
+		t.Error("expected a non-annotation --filter to be refused")
+	}
+	if got := annotationWarnings([]string{"io.kubernetes.pod.name=mypod", "mykey=1", "a.b=2"}); len(got) != 2 {
+		t.Errorf("annotationWarnings = %q, want warnings for mykey and a.b", got)
+	}
+}
+
+func TestKeepIDMap(t *testing.T) {
+	m := keepIDMap{own: 1000, sub: idRange{Start: 100000, Count: 65536}}
+	for c, want := range map[int]int{0: 100000, 999: 100999, 1000: 1000, 1001: 101000, 65536: 165535} {
+		if got, ok := m.host(c); !ok || got != want {
+			t.Errorf("host(%d) = %d, %v; want %d", c, got, ok, want)
+		}
+	}
+	if _, ok := m.host(65537); ok {
+		t.Error("host(65537) should be beyond a 65536-id range")
+	}
+	for h, want := range map[int]bool{0: false, 1000: true, 100000: true, 165535: true, 165536: false} {
+		if got := m.mapped(h); got != want {
+			t.Errorf("mapped(%d) = %v, want %v", h, got, want)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "subuid")
+	if err := os.WriteFile(file, []byte("alice:100000:65536\n1001:165536:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := subordinateRange(file, "bob", 1001); err != nil || r != (idRange{165536, 65536}) {
+		t.Errorf("subordinateRange(bob) = %v, %v; want the entry under bob's uid", r, err)
+	}
+	if _, err := subordinateRange(file, "carol", 1002); err == nil {
+		t.Error("expected no range for a user the file doesn't list")
+	}
+
+	tree := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tree, "usr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	own := keepIDMap{own: os.Getuid(), sub: idRange{Start: 100000, Count: 65536}}
+	ownGID := keepIDMap{own: os.Getgid(), sub: idRange{Start: 100000, Count: 65536}}
+	st, err := chownTree(tree, own, ownGID, true, nil)
+	if err != nil || st.shifted != 0 || st.skipped != 2 {
+		t.Errorf("chownTree on a tree already owned by the user = %+v, %v; want everything skipped", st, err)
+	}
+}
+
+func TestUserNSHints(t *testing.T) {
+	procSys := t.TempDir()
+	if hints := userNSHints(procSys); len(hints) != 0 {
+		t.Errorf("no sysctl files: got hints %q", hints)
+	}
+	for name, val := range map[string]string{
+		"user/max_user_namespaces":                     "0\n",
+		"kernel/unprivileged_userns_clone":             "1\n",
+		"kernel/apparmor_restrict_unprivileged_userns": "1\n",
+	} {
+		path := filepath.Join(procSys, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(val), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hints := userNSHints(procSys)
+	if len(hints) != 2 || !strings.Contains(hints[0], "max_user_namespaces") || !strings.Contains(hints[1], "AppArmor") {
+		t.Errorf("userNSHints = %q; want the max_user_namespaces and AppArmor hints only", hints)
+	}
+}
+
+func TestMACFromSeed(t *testing.T) {
+	a, b := macFromSeed("host/isolator-web"), macFromSeed("host/isolator-web")
+	if a.String() != b.String() {
+		t.Errorf("macFromSeed isn't deterministic: %s vs %s", a, b)
+	}
+	if len(a) != 6 || a[0]&0x02 == 0 || a[0]&0x01 != 0 {
+		t.Errorf("macFromSeed = %s; want a 6-byte locally administered unicast address", a)
+	}
+	if c := macFromSeed("other/isolator-web"); c.String() == a.String() {
+		t.Errorf("different seeds gave the same address %s", a)
+	}
+	args := strings.Join(buildMACAddressArgs("isolator-web", "macvlan:eth0", ""), " ")
+	if args != "--mac-address "+autoMAC("isolator-web").String() {
+		t.Errorf("default macvlan args = %q; want the auto address", args)
+	}
+	if args := buildMACAddressArgs("isolator-web", "macvlan:eth0", "random"); args != nil {
+		t.Errorf("random: got %q, want no --mac-address", args)
+	}
+	if args := buildMACAddressArgs("isolator-web", "", ""); args != nil {
+		t.Errorf("NAT network: got %q, want no --mac-address", args)
+	}
+}
+
+func TestCheckDevicesOOMKillDisable(t *testing.T) {
+	err := RunOptions{OOMKillDisable: true}.CheckDevices()
+	noProtection := len(buildOOMArgs(true)) == 0
+	if noProtection && err == nil {
+		t.Errorf("--oom-kill-disable accepted on a host where it adds nothing")
+	}
+	if !noProtection && err != nil {
+		t.Errorf("--oom-kill-disable refused on a host that supports it: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package src
+
+import (
+	"fmt"
+	"os"
+)
+
+// shellScript picks the best shell the container has — bash, then ash,
+// then plain sh — checking inside the container, since images differ
+// (debian has bash, alpine and busybox images only ash, distroless-ish
+// ones nothing but sh). bash gets a throwaway --rcfile that reads the
+// usual system and user rc files first and only then sets the prompt,
+// because distro bashrcs (Debian's /etc/bash.bashrc among them) assign
+// PS1 unconditionally and would hide the one passed in the environment.
+// ash and sh take PS1 from the environment as is.
+func shellScript(cont string) string {
+	bashPS1 := fmt.Sprintf(`(isolator:%s) \u@\h:\w\$ `, cont)
+	return `if command -v bash >/dev/null 2>&1; then
+  rc=$(mktemp) || exec bash -i
+  cat > "$rc" <<'EOF'
+[ -f /etc/bash.bashrc ] && . /etc/bash.bashrc
+[ -f /etc/bashrc ] && . /etc/bashrc
+[ -f ~/.bashrc ] && . ~/.bashrc
+PS1='` + bashPS1 + `'
+EOF
+  printf 'rm -f -- %s\n' "$rc" >> "$rc"
+  exec bash --rcfile "$rc" -i
+fi
+if command -v ash >/dev/null 2>&1; then exec ash -i; fi
+exec sh -i`
+}
+
+// resolveShellTarget accepts an installed package (its container) or the
+// name of a managed container directly.
+func resolveShellTarget(target string) (string, error) {
+	if installed, err := LoadInstalled(); err == nil {
+		for _, ip := range installed {
+			if ip.Pkg == target {
+				return ip.Cont, nil
+			}
+		}
+	}
+	for _, c := range GetOurContainers() {
+		if c == target {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is neither an installed package nor a managed container", target)
+}
+
+// HandleShell opens an interactive shell in target's container, always
+// with a TTY, and returns the shell's exit code (see HandleExec). The
+// prompt names the container, so it's obvious which side of the boundary
+// a terminal is on.
+func HandleShell(target string, opts ExecOptions) int {
+	if err := opts.Validate(); err != nil {
+		PrintError(err.Error())
+		return execFailedCode
+	}
+	cont, err := resolveShellTarget(target)
+	if err != nil {
+		PrintError(err.Error())
+		return execFailedCode
+	}
+	stdio := DefaultExecStdio()
+	stdio.Interactive, stdio.TTY = true, true
+	opts.Env = append([]string{fmt.Sprintf("PS1=(isolator:%s) $ ", cont)}, opts.Env...)
+	fmt.Fprintln(os.Stderr, DimStyle.Render("Shell in "+cont+" — exit or Ctrl-D to leave"))
+	return execInContainer(cont, "/bin/sh", []string{"-c", shellScript(cont)}, stdio, opts)
+}
@@ -3,9 +3,12 @@ package src
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,6 +30,9 @@ func snapshotsFile() string {
 //	--> container  => fedora
 //	--> image      => isolator-snapshot/fedora:1731000000
 //	--> created_at => 1731000000
+//
+// A second snapshot of fedora within the same second is tagged
+// fedora:1731000000-1 and keyed fedora@1731000000-1.
 func loadSnapshots() []SnapshotRecord {
 	path := snapshotsFile()
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -67,7 +73,7 @@ func saveSnapshots(recs []SnapshotRecord) error {
 		m.Set("container", hkStr(r.Container))
 		m.Set("image", hkStr(r.Image))
 		m.Set("created_at", hkNum(float64(r.CreatedAt.Unix())))
-		key := r.Container + "@" + strconv.FormatInt(r.CreatedAt.Unix(), 10)
+		key := r.Container + "@" + snapshotID(r)
 		sec.Set(key, HkValue{Kind: HkMapKind, MapVal: m})
 	}
 	return WriteHKFile(snapshotsFile(), doc)
@@ -77,7 +83,9 @@ func latestSnapshotFor(cont string, recs []SnapshotRecord) *SnapshotRecord {
 	var latest *SnapshotRecord
 	for i := range recs {
 		if recs[i].Container == cont {
-			if latest == nil || recs[i].CreatedAt.After(latest.CreatedAt) {
+			// Records are kept in the order they were taken, so of two
+			// from the same second the later one wins.
+			if latest == nil || !recs[i].CreatedAt.Before(latest.CreatedAt) {
 				latest = &recs[i]
 			}
 		}
@@ -106,12 +114,19 @@ func HandleSnapshot(cont string, dryRun bool) {
 // snapshotOne does the actual commit + bookkeeping for a single container,
 // shared by HandleSnapshot and HandleSnapshotAll.
 func snapshotOne(cont string) (string, error) {
-	tag := fmt.Sprintf("isolator-snapshot/%s:%d", cont, time.Now().Unix())
+	recs := loadSnapshots()
+	tag := snapshotTag(cont, time.Now().Unix(), func(tag string) bool {
+		for _, r := range recs {
+			if r.Image == tag {
+				return true
+			}
+		}
+		return ImageExists(tag)
+	})
 	PrintStep("Committing snapshot of " + cont + "...")
 	if !ExecCommand(podmanBin, []string{"commit", cont, tag}) {
 		return "", fmt.Errorf("snapshot of '%s' failed", cont)
 	}
-	recs := loadSnapshots()
 	recs = append(recs, SnapshotRecord{Container: cont, Image: tag, CreatedAt: time.Now()})
 	if err := saveSnapshots(recs); err != nil {
 		return "", fmt.Errorf("snapshot of '%s' created but failed to record metadata: %w", cont, err)
@@ -120,6 +135,29 @@ func snapshotOne(cont string) (string, error) {
 	return tag, nil
 }
 
+// snapshotTag names the image for a snapshot of cont taken at unix. podman
+// commit silently moves an existing tag to the new image, which would leave
+// the older record (two snapshots within a second, or a clock that went
+// backwards) rolling back to the wrong state, so a tag that's taken gets a
+// counter appended instead.
+func snapshotTag(cont string, unix int64, taken func(string) bool) string {
+	base := fmt.Sprintf("isolator-snapshot/%s:%d", cont, unix)
+	tag := base
+	for n := 1; taken(tag); n++ {
+		tag = fmt.Sprintf("%s-%d", base, n)
+	}
+	return tag
+}
+
+// snapshotID is the part of r's record key after the container name: the
+// image tag, or for a record without one, its creation time.
+func snapshotID(r SnapshotRecord) string {
+	if i := strings.LastIndex(r.Image, ":"); i >= 0 && !strings.Contains(r.Image[i:], "/") {
+		return r.Image[i+1:]
+	}
+	return strconv.FormatInt(r.CreatedAt.Unix(), 10)
+}
+
 // HandleSnapshotAll snapshots every container Isolator manages in one go —
 // the natural "before I upgrade/rollback everything" preparation step for a
 // real system-wide rollback later.
@@ -197,17 +235,89 @@ func rollbackOne(cont string, latest *SnapshotRecord) error {
 		}
 	}
 
+	// A container with no record is recreated without the defaults added
+	// since it was made (see RunOptions.Unrecorded).
+	opts, _ := LoadContainerOptions(cont)
+	// The snapshot image holds the container's whole filesystem, so a
+	// container made from a --rootfs tree comes back from it, not the tree.
+	opts.RootFS = ""
+	if target := opts.PIDTarget(); target != "" && !EnsureContainerRunning(target) {
+		return fmt.Errorf("rollback of '%s' needs container '%s' running (it shares that container's PID namespace)", cont, target)
+	}
+	if opts.Network != "" {
+		if _, err := ensureL2Network(opts.Network, opts.IP); err != nil {
+			return fmt.Errorf("rollback of '%s': %v", cont, err)
+		}
+	}
+	if opts.MachineID == "" {
+		if err := ensureMachineID(cont); err != nil {
+			return fmt.Errorf("rollback of '%s': %v", cont, err)
+		}
+	}
+
+	vols := anonymousVolumes(cont)
 	ExecCommand(podmanBin, []string{"stop", cont})
 	ExecCommand(podmanBin, []string{"rm", "--force", cont})
 
-	args := getPodmanRunArgs(cont, latest.Image, homeDir, pkgType, initSystem)
+	args := withVolumes(getPodmanRunArgs(cont, latest.Image, homeDir, pkgType, initSystem, opts), vols)
 	if !ExecCommand(podmanBin, args) {
+		explainCreateFailure()
+		for _, v := range vols {
+			PrintWarn("Volume data is kept in " + v + " (podman volume rm it once it's no longer needed)")
+		}
 		return fmt.Errorf("rollback of '%s' failed to recreate the container", cont)
 	}
 	PrintSuccess("Rollback complete: " + cont + " restored from " + latest.Image)
 	return nil
 }
 
+// anonymousVolumeRe matches the random names podman gives the volumes it
+// creates for an image's VOLUME paths; named volumes the user mounted come
+// back through the container's own options.
+var anonymousVolumeRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// anonymousVolumes lists cont's anonymous volumes as NAME:DEST. A snapshot
+// doesn't contain them (podman commit skips volumes), so rollback hands
+// them to the recreated container rather than letting it start on fresh,
+// empty ones and leaving the old ones behind unreferenced.
+func anonymousVolumes(cont string) []string {
+	out, err := exec.Command(podmanBin, "container", "inspect", "--format", `{{range .Mounts}}{{if eq .Type "volume"}}{{.Name}} {{.Destination}}{{"\n"}}{{end}}{{end}}`, cont).Output()
+	if err != nil {
+		return nil
+	}
+	return parseAnonymousVolumes(string(out))
+}
+
+func parseAnonymousVolumes(out string) []string {
+	var vols []string
+	for _, line := range strings.Split(out, "\n") {
+		name, dest, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && anonymousVolumeRe.MatchString(name) {
+			vols = append(vols, name+":"+dest)
+		}
+	}
+	return vols
+}
+
+// withVolumes adds a --volume for each of vols to `podman run` args,
+// right after "run" so it precedes the image.
+func withVolumes(args, vols []string) []string {
+	if len(vols) == 0 {
+		return args
+	}
+	for i, a := range args {
+		if a != "run" {
+			continue
+		}
+		out := append([]string{}, args[:i+1]...)
+		for _, v := range vols {
+			out = append(out, "--volume", v)
+		}
+		return append(out, args[i+1:]...)
+	}
+	return args
+}
+
 // HandleRollbackAll is the real system-wide rollback: every managed
 // container that has at least one recorded snapshot gets restored to its
 // own latest snapshot. Containers with no snapshot are reported and
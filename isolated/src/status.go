@@ -1,24 +1,46 @@
 package src
 
 import (
+	"bufio"
+	"fmt"
+	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-func HandleStatus() {
+var statusColumns = []table.Column{
+	{Title: "Container", Width: 26},
+	{Title: "Status", Width: 12},
+	{Title: "Size", Width: 18},
+	{Title: "Runtime", Width: 8},
+	{Title: "Packages", Width: 30},
+}
+
+// parseStatusFilters checks --filter values; annotation=KEY[=VALUE] is the
+// only kind, matched against the annotations the container was created
+// with.
+func parseStatusFilters(filters []string) ([]string, error) {
+	var annotations []string
+	for _, f := range filters {
+		kind, a, _ := strings.Cut(f, "=")
+		if kind != "annotation" || a == "" || strings.HasPrefix(a, "=") {
+			return nil, fmt.Errorf("--filter %q is not supported (expected annotation=KEY or annotation=KEY=VALUE)", f)
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
+func statusRows(annotations []string) []table.Row {
 	installed, _ := LoadInstalled()
 	pkgMap := map[string][]string{}
 	for _, ip := range installed {
 		pkgMap[ip.Cont] = append(pkgMap[ip.Cont], ip.Pkg)
 	}
 
-	columns := []table.Column{
-		{Title: "Container", Width: 26},
-		{Title: "Status", Width: 12},
-		{Title: "Size", Width: 18},
-		{Title: "Packages", Width: 34},
-	}
 	var rows []table.Row
 	for _, db := range GetContainers() {
 		for _, name := range db.Names {
@@ -32,13 +54,141 @@ func HandleStatus() {
 			if !isOur {
 				continue
 			}
+			if !statusFilterMatches(name, annotations) {
+				continue
+			}
 			size := GetContainerSize(name)
-			rows = append(rows, []string{name, db.State, size, strings.Join(pkgMap[name], ", ")})
+			rows = append(rows, []string{name, db.State, size, GetContainerRuntime(name), strings.Join(pkgMap[name], ", ")})
 		}
 	}
+	return rows
+}
+
+func statusFilterMatches(name string, annotations []string) bool {
+	if len(annotations) == 0 {
+		return true
+	}
+	opts, _ := LoadContainerOptions(name)
+	have := opts.Annotations
+	for _, a := range annotations {
+		if !annotationMatches(have, a) {
+			return false
+		}
+	}
+	return true
+}
+
+func HandleStatus(watch bool, filters []string) {
+	annotations, err := parseStatusFilters(filters)
+	if err != nil {
+		PrintError(err.Error())
+		return
+	}
+	if watch {
+		watchStatus(annotations)
+		return
+	}
+	rows := statusRows(annotations)
 	if len(rows) == 0 {
 		PrintInfo("No managed containers found")
 		return
 	}
-	RunTable("Container Status", columns, rows)
+	RunTable("Container Status", statusColumns, rows)
+}
+
+// statusPollInterval is how often a watched status refreshes anyway —
+// sizes change without any podman event, and it's the only refresh left
+// if `podman events` isn't available.
+const statusPollInterval = 5 * time.Second
+
+type statusChangedMsg struct{}
+
+// statusWatchModel is the table from HandleStatus, redrawn in place
+// whenever podman reports a container event (create, start, die, remove,
+// ...) and every statusPollInterval.
+type statusWatchModel struct {
+	table       table.Model
+	events      <-chan struct{}
+	annotations []string
+}
+
+func waitForStatusChange(events <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case _, ok := <-events:
+			if ok {
+				return statusChangedMsg{}
+			}
+			// podman events exited; keep polling only.
+			time.Sleep(statusPollInterval)
+		case <-time.After(statusPollInterval):
+		}
+		return statusChangedMsg{}
+	}
+}
+
+func (m statusWatchModel) Init() tea.Cmd { return waitForStatusChange(m.events) }
+
+func (m statusWatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+	case statusChangedMsg:
+		m.table.SetRows(statusRows(m.annotations))
+		return m, waitForStatusChange(m.events)
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m statusWatchModel) View() string {
+	titleBar := TitleStyle.Render(" Container Status (watching) ")
+	body := m.table.View()
+	if len(m.table.Rows()) == 0 {
+		body += "\n" + DimStyle.Render("  No managed containers yet")
+	}
+	footer := DimStyle.Render("  ↑/↓ navigate   q quit   updates as containers change")
+	return "\n" + titleBar + "\n\n" + body + "\n\n" + footer + "\n"
+}
+
+// watchStatus follows `podman events` for container events. Podman is
+// where the state actually lives, so its event stream is what changes
+// whenever anything does, whoever made the change. Bursts (an install
+// creates, starts and inits in quick succession) collapse into one
+// redraw because the channel holds at most one pending notification.
+func watchStatus(annotations []string) {
+	events := make(chan struct{}, 1)
+	cmd := exec.Command(podmanBin, "events", "--filter", "type=container", "--format", "{{.Status}}")
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		PrintWarn("Can't follow podman events (" + err.Error() + ") — refreshing every " + statusPollInterval.String() + " instead")
+		close(events)
+	} else {
+		defer func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}()
+		go func() {
+			defer close(events)
+			sc := bufio.NewScanner(stdout)
+			for sc.Scan() {
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	t := buildStyledTable(statusColumns, statusRows(annotations), 20)
+	if _, err := tea.NewProgram(statusWatchModel{table: t, events: events, annotations: annotations}).Run(); err != nil {
+		PrintError("status --watch needs an interactive terminal: " + err.Error())
+	}
 }
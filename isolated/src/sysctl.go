@@ -0,0 +1,59 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// namespacedSysctls are the sysctl prefixes that belong to a namespace the
+// container has of its own (network for net.*, IPC for the rest), so
+// setting them changes the container alone. kernel.sem is a single key; the
+// others are prefixes.
+var namespacedSysctls = []string{"net.", "fs.mqueue.", "kernel.msg", "kernel.sem", "kernel.shm"}
+
+// sysctlKeyRe is a dotted sysctl name. Interface names appear as a
+// component (net.ipv4.conf.eth0.rp_filter), so dashes are allowed; a "/"
+// form, or anything that could climb out of /proc/sys, isn't.
+var sysctlKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-zA-Z0-9_-]+)+$`)
+
+// validateSysctls checks --sysctl entries, KEY=VALUE. Keys outside the
+// namespaced prefixes are refused outright: podman would either reject
+// them or, for a shared namespace, let the container change the host.
+func validateSysctls(entries []string) error {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		key, val, ok := strings.Cut(e, "=")
+		if !ok || val == "" || !sysctlKeyRe.MatchString(key) {
+			return fmt.Errorf("--sysctl %q should be KEY=VALUE with KEY a dotted name like net.core.somaxconn", e)
+		}
+		if !sysctlNamespaced(key) {
+			return fmt.Errorf("--sysctl %s isn't namespaced, so setting it would change the whole host (only net.*, fs.mqueue.*, kernel.msg*, kernel.sem and kernel.shm* can be set per container)", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("--sysctl: %s is set twice", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func sysctlNamespaced(key string) bool {
+	for _, p := range namespacedSysctls {
+		if key == p || (p != "kernel.sem" && strings.HasPrefix(key, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSysctlArgs passes the entries to podman, which keeps them in the
+// container's OCI spec: the runtime writes them into the container's
+// namespaces every time it starts, restarts included.
+func buildSysctlArgs(entries []string) []string {
+	var args []string
+	for _, e := range entries {
+		args = append(args, "--sysctl", e)
+	}
+	return args
+}
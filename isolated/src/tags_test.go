@@ -0,0 +1,32 @@
+package src
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTagOrder(t *testing.T) {
+	tags := []string{"latest", "1.9", "1.10.0-rc1", "v2.0", "1.10.0", "edge", "1.10"}
+	sort.Slice(tags, func(i, j int) bool { return tagLess(tags[i], tags[j]) })
+	want := "v2.0 1.10 1.10.0 1.10.0-rc1 1.9 edge latest"
+	if got := strings.Join(tags, " "); got != want {
+		t.Errorf("sorted tags = %q, want %q", got, want)
+	}
+}
+
+func TestImageRepoMatches(t *testing.T) {
+	for _, c := range []struct {
+		local, repo string
+		want        bool
+	}{
+		{"docker.io/library/alpine", "alpine", true},
+		{"docker.io/myorg/tool", "myorg/tool", true},
+		{"ghcr.io/myorg/tool", "ghcr.io/myorg/tool", true},
+		{"ghcr.io/myorg/tool", "myorg/tool", false},
+	} {
+		if got := imageRepoMatches(c.local, c.repo); got != c.want {
+			t.Errorf("imageRepoMatches(%q, %q) = %v, want %v", c.local, c.repo, got, c.want)
+		}
+	}
+}
@@ -4,10 +4,212 @@ import (
 	"fmt"
 	"isolator/src"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// runOptionsFromFlags starts from config.hk's [container] defaults, layers
+// the --profile (if any) on top, and overrides whichever options were
+// given explicitly on the command line.
+func runOptionsFromFlags(cmd *cobra.Command) src.RunOptions {
+	opts := src.RunOptionsFromConfig(src.LoadConfig())
+	if name, _ := cmd.Flags().GetString("profile"); name != "" {
+		var err error
+		if opts, err = src.ApplyProfile(opts, name); err != nil {
+			src.PrintError(err.Error())
+			os.Exit(1)
+		}
+	}
+	if cmd.Flags().Changed("tz") {
+		opts.Timezone, _ = cmd.Flags().GetString("tz")
+	}
+	if cmd.Flags().Changed("locale") {
+		opts.Locale, _ = cmd.Flags().GetString("locale")
+	}
+	if cmd.Flags().Changed("runtime") {
+		opts.Runtime, _ = cmd.Flags().GetString("runtime")
+	}
+	if cmd.Flags().Changed("no-passwd-entry") {
+		opts.NoPasswdEntry, _ = cmd.Flags().GetBool("no-passwd-entry")
+	}
+	if cmd.Flags().Changed("passwd-file") {
+		opts.PasswdFile = hostPathFlag(cmd, "passwd-file")
+	}
+	if cmd.Flags().Changed("group-file") {
+		opts.GroupFile = hostPathFlag(cmd, "group-file")
+	}
+	if cmd.Flags().Changed("no-containerenv") {
+		opts.NoContainerEnv, _ = cmd.Flags().GetBool("no-containerenv")
+	}
+	if cmd.Flags().Changed("no-hosts") {
+		opts.NoHosts, _ = cmd.Flags().GetBool("no-hosts")
+	}
+	if cmd.Flags().Changed("no-resolv-conf") {
+		opts.NoResolvConf, _ = cmd.Flags().GetBool("no-resolv-conf")
+	}
+	if cmd.Flags().Changed("seccomp-profile") {
+		if opts.SeccompProfile, _ = cmd.Flags().GetString("seccomp-profile"); opts.SeccompProfile != "unconfined" {
+			opts.SeccompProfile = hostPathFlag(cmd, "seccomp-profile")
+		}
+	}
+	if cmd.Flags().Changed("group-add") {
+		opts.GroupAdd, _ = cmd.Flags().GetString("group-add")
+	}
+	if cmd.Flags().Changed("rootfs") {
+		spec, _ := cmd.Flags().GetString("rootfs")
+		// Keep podman's :O suffix off the path being made absolute.
+		dir, overlay := strings.CutSuffix(spec, ":O")
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if opts.RootFS = dir; overlay {
+			opts.RootFS += ":O"
+		}
+	}
+	if cmd.Flags().Changed("machine-id") {
+		opts.MachineID, _ = cmd.Flags().GetString("machine-id")
+	}
+	if cmd.Flags().Changed("no-image-volumes") {
+		opts.NoImageVolumes, _ = cmd.Flags().GetBool("no-image-volumes")
+	}
+	if cmd.Flags().Changed("cap-drop-all") {
+		opts.CapDropAll, _ = cmd.Flags().GetBool("cap-drop-all")
+	}
+	if cmd.Flags().Changed("cap-add") {
+		opts.CapAdd, _ = cmd.Flags().GetStringSlice("cap-add")
+		if len(opts.CapAdd) == 0 {
+			opts.CapAdd = nil
+		}
+	}
+	if cmd.Flags().Changed("device-input") {
+		opts.DeviceInput, _ = cmd.Flags().GetBool("device-input")
+	}
+	if cmd.Flags().Changed("device-kvm") {
+		opts.DeviceKVM, _ = cmd.Flags().GetBool("device-kvm")
+	}
+	if cmd.Flags().Changed("device-video") {
+		opts.DeviceVideo, _ = cmd.Flags().GetBool("device-video")
+	}
+	if cmd.Flags().Changed("privileged-devices") {
+		opts.PrivilegedDevices, _ = cmd.Flags().GetBool("privileged-devices")
+	}
+	if cmd.Flags().Changed("mask-path") {
+		opts.MaskPaths, _ = cmd.Flags().GetStringArray("mask-path")
+	}
+	if cmd.Flags().Changed("no-mask-paths") {
+		opts.NoMaskPaths, _ = cmd.Flags().GetBool("no-mask-paths")
+	}
+	// Docker's spelling of --no-mask-paths: podman's unmask=ALL lifts the
+	// same masks and read-only /proc paths that systempaths=unconfined
+	// does. It's the only --security-opt install takes; the rest (labels,
+	// seccomp, apparmor) are chosen per package type and GPU setup.
+	secOpts, _ := cmd.Flags().GetStringArray("security-opt")
+	for _, so := range secOpts {
+		if so != "systempaths=unconfined" {
+			src.PrintError(fmt.Sprintf("--security-opt %q is not supported (only systempaths=unconfined)", so))
+			os.Exit(1)
+		}
+		opts.NoMaskPaths = true
+	}
+	if cmd.Flags().Changed("device-fuse") {
+		opts.DeviceFUSE, _ = cmd.Flags().GetBool("device-fuse")
+	}
+	if cmd.Flags().Changed("usb") {
+		opts.USB, _ = cmd.Flags().GetStringArray("usb")
+	}
+	if cmd.Flags().Changed("device-cgroup-rule") {
+		opts.DeviceCgroupRules, _ = cmd.Flags().GetStringArray("device-cgroup-rule")
+	}
+	if cmd.Flags().Changed("cgroupns") {
+		opts.CgroupNS, _ = cmd.Flags().GetString("cgroupns")
+	}
+	if cmd.Flags().Changed("cgroup-rw") {
+		opts.CgroupRW, _ = cmd.Flags().GetBool("cgroup-rw")
+	}
+	if cmd.Flags().Changed("storage-size") {
+		opts.StorageSize, _ = cmd.Flags().GetString("storage-size")
+	}
+	if cmd.Flags().Changed("mount") {
+		opts.Mounts, _ = cmd.Flags().GetStringArray("mount")
+	}
+	if cmd.Flags().Changed("pid") {
+		opts.PID, _ = cmd.Flags().GetString("pid")
+	}
+	if cmd.Flags().Changed("publish") {
+		opts.Publish, _ = cmd.Flags().GetStringArray("publish")
+	}
+	if cmd.Flags().Changed("device-read-iops") {
+		opts.DeviceReadIOPS, _ = cmd.Flags().GetStringArray("device-read-iops")
+	}
+	if cmd.Flags().Changed("device-write-iops") {
+		opts.DeviceWriteIOPS, _ = cmd.Flags().GetStringArray("device-write-iops")
+	}
+	if cmd.Flags().Changed("cpu-shares") {
+		opts.CPUShares, _ = cmd.Flags().GetInt("cpu-shares")
+	}
+	if cmd.Flags().Changed("cpu-weight") {
+		opts.CPUWeight, _ = cmd.Flags().GetInt("cpu-weight")
+	}
+	if cmd.Flags().Changed("memory-node-pin") {
+		opts.MemoryNodes, _ = cmd.Flags().GetString("memory-node-pin")
+	}
+	if cmd.Flags().Changed("umask") {
+		opts.Umask, _ = cmd.Flags().GetString("umask")
+	}
+	if cmd.Flags().Changed("sysfs") {
+		opts.SysFS, _ = cmd.Flags().GetString("sysfs")
+	}
+	if cmd.Flags().Changed("label") {
+		opts.Labels, _ = cmd.Flags().GetStringArray("label")
+	}
+	if cmd.Flags().Changed("label-namespace") {
+		opts.LabelNamespace, _ = cmd.Flags().GetString("label-namespace")
+	}
+	if cmd.Flags().Changed("annotation") {
+		opts.Annotations, _ = cmd.Flags().GetStringArray("annotation")
+	}
+	if cmd.Flags().Changed("ulimit") {
+		opts.Ulimits, _ = cmd.Flags().GetStringArray("ulimit")
+	}
+	if cmd.Flags().Changed("network") {
+		opts.Network, _ = cmd.Flags().GetString("network")
+	}
+	if cmd.Flags().Changed("ip") {
+		opts.IP, _ = cmd.Flags().GetString("ip")
+	}
+	if cmd.Flags().Changed("mac-address") {
+		opts.MACAddress, _ = cmd.Flags().GetString("mac-address")
+	}
+	if cmd.Flags().Changed("cgroup-conf") {
+		opts.CgroupConf, _ = cmd.Flags().GetStringArray("cgroup-conf")
+	}
+	if cmd.Flags().Changed("sysctl") {
+		opts.Sysctls, _ = cmd.Flags().GetStringArray("sysctl")
+	}
+	if cmd.Flags().Changed("proc-opts") {
+		opts.ProcOpts, _ = cmd.Flags().GetString("proc-opts")
+	}
+	if opts.OOMKillDisable, _ = cmd.Flags().GetBool("oom-kill-disable"); opts.OOMKillDisable {
+		if ack, _ := cmd.Flags().GetBool("i-understand-oom-risk"); !ack {
+			src.PrintError("--oom-kill-disable can hang the whole host if the container runs it out of memory; add --i-understand-oom-risk to confirm")
+			os.Exit(1)
+		}
+	}
+	return opts
+}
+
+// hostPathFlag reads a flag naming a host file, made absolute so it means
+// the same thing when podman later mounts it.
+func hostPathFlag(cmd *cobra.Command, name string) string {
+	path, _ := cmd.Flags().GetString(name)
+	if abs, err := filepath.Abs(path); err == nil && path != "" {
+		return abs
+	}
+	return path
+}
+
 func main() {
 	// --version, -h/--help, and bare `help` shouldn't require podman to be
 	// installed — someone checking "what version is this" or reading the
@@ -30,6 +232,7 @@ func main() {
 			src.PrintError(err.Error())
 			os.Exit(1)
 		}
+		src.ApplyProxyConfig(src.LoadConfig())
 	}
 
 	// `isolator myproject.hk` — declarative dev environment activation,
@@ -62,11 +265,63 @@ func main() {
 				isolated = src.LoadConfig().DefaultIsolated
 			}
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
-			src.HandleInstall(args[0], isolated, dryRun)
+			src.HandleInstall(args[0], isolated, dryRun, runOptionsFromFlags(cmd))
 		},
 	}
 	installCmd.Flags().Bool("isolated", false, "Install in isolated container with its own home directory")
 	installCmd.Flags().Bool("dry-run", false, "Show what would happen without installing anything")
+	installCmd.Flags().String("profile", "", "Apply a named profile from config.hk's [profiles] section (explicit flags still override it)")
+	installCmd.Flags().String("tz", "", "Container timezone: 'local' (follow the host) or a zone name like Europe/Warsaw (default: config.hk, else UTC)")
+	installCmd.Flags().String("locale", "", "Container locale: 'host' passes LANG/LC_* through (default: config.hk, else the image's)")
+	installCmd.Flags().String("runtime", "", "OCI runtime podman should use for a new container, e.g. crun, runc or youki (looked up on PATH)")
+	installCmd.Flags().Bool("no-passwd-entry", false, "Don't add passwd/group entries for your user to a new container (for images that manage users via NSS)")
+	installCmd.Flags().String("passwd-file", "", "Host file to mount read-only over a new container's /etc/passwd (checked for passwd(5) format)")
+	installCmd.Flags().String("group-file", "", "Host file to mount read-only over a new container's /etc/group (checked for group(5) format)")
+	installCmd.Flags().Bool("no-hosts", false, "Don't generate /etc/hosts in a new container; keep the image's")
+	installCmd.Flags().Bool("no-resolv-conf", false, "Don't generate /etc/resolv.conf in a new container; keep the image's")
+	installCmd.Flags().String("seccomp-profile", "", "Seccomp profile (Docker's JSON format) for a new container instead of podman's default, or 'unconfined'")
+	installCmd.Flags().String("group-add", "", "'keep-groups' to keep your host supplementary groups (e.g. docker, for a bind-mounted socket) in a new container")
+	installCmd.Flags().String("rootfs", "", "Create a new container from this host root filesystem tree instead of the distro's image; add :O to keep the tree unmodified")
+	installCmd.Flags().String("machine-id", "", "A new container's /etc/machine-id: 'host' (share the host's) or 'none' (keep the image's); default: one generated for it")
+	installCmd.Flags().Bool("no-image-volumes", false, "Don't give the image's VOLUME paths anonymous volumes in a new container; keep them in its own filesystem")
+	installCmd.Flags().Bool("no-containerenv", false, "Blank out /run/.containerenv (container metadata) inside a new container")
+	installCmd.Flags().Bool("cap-drop-all", false, "Start a new container with no capabilities; add back only what it needs with --cap-add")
+	installCmd.Flags().StringSlice("cap-add", nil, "Capability to grant a new container, e.g. NET_BIND_SERVICE (repeatable or comma-separated)")
+	installCmd.Flags().Bool("device-input", false, "Share gamepads, joysticks and other input devices (/dev/input, /dev/uinput, udev data) with a new container")
+	installCmd.Flags().Bool("device-video", false, "Share webcams and capture devices (/dev/video*, /dev/media*) with a new container")
+	installCmd.Flags().Bool("privileged-devices", false, "Share GPU, video and sound devices (/dev/dri, /dev/nvidia*, /dev/video*, /dev/snd) with a new container, without --privileged")
+	installCmd.Flags().Bool("device-fuse", false, "Share /dev/fuse with a new container so AppImages, sshfs and other FUSE mounts work inside it")
+	installCmd.Flags().StringArray("usb", nil, "Pass a USB device through to a new container, as vendor:product or bus.device from lsusb (repeatable)")
+	installCmd.Flags().StringArray("device-cgroup-rule", nil, "Allow devices matching a rule like 'c 189:* rwm' in a new container, passing matching host devices through (repeatable)")
+	installCmd.Flags().Bool("cgroup-rw", false, "Mount a new container's own /sys/fs/cgroup read-write, for systemd or other cgroup managers inside it")
+	installCmd.Flags().String("cgroupns", "", "Cgroup namespace for a new container: 'private' (own cgroup2 tree at /sys/fs/cgroup), 'host', or 'auto' (private if the kernel supports it)")
+	installCmd.Flags().String("storage-size", "", "Cap a new container's writable layer, e.g. 20G (needs overlay storage on XFS)")
+	installCmd.Flags().StringArray("mount", nil, "Add a mount to a new container, e.g. type=bind,source=/srv/data,target=/data,readonly (repeatable)")
+	installCmd.Flags().String("pid", "", "Join another container's PID namespace, as container:NAME (e.g. to strace or gdb its processes)")
+	installCmd.Flags().StringArrayP("publish", "p", nil, "Forward host ports into a new container: [HOST_IP:]HOST:CONTAINER[/udp], ranges like 8000-8010:8000-8010 allowed (repeatable)")
+	installCmd.Flags().StringArray("device-read-iops", nil, "Cap a new container's reads per second from a block device, as DEVICE:IOPS (repeatable)")
+	installCmd.Flags().StringArray("device-write-iops", nil, "Cap a new container's writes per second to a block device, as DEVICE:IOPS (repeatable)")
+	installCmd.Flags().Int("cpu-shares", 0, "Relative CPU weight of a new container on Docker's scale, 2-262144 (default 1024)")
+	installCmd.Flags().Int("cpu-weight", 0, "Relative CPU weight of a new container on cgroup v2's scale, 1-10000 (default 100)")
+	installCmd.Flags().String("memory-node-pin", "", "Pin a new container's memory to these NUMA nodes, e.g. 0 or 0-1 or 0,2")
+	installCmd.Flags().String("sysfs", "", "What a new container sees of /sys, always read-only: 'read-only' (podman's /sys masks, the default), 'masked' (also hide /sys/kernel/security and /sys/kernel/debug) or 'unconfined' (no /sys masks)")
+	installCmd.Flags().StringArray("label", nil, "Add a key=value label to a new container (repeatable)")
+	installCmd.Flags().StringArray("annotation", nil, "Add a key=value OCI annotation to a new container, e.g. io.kubernetes.pod.name=mypod (repeatable)")
+	installCmd.Flags().String("label-namespace", "", "Reverse-DNS prefix for --label keys, e.g. com.example (team=x becomes com.example.team=x)")
+	installCmd.Flags().StringArray("ulimit", nil, "Resource limit for a new container, TYPE=SOFT[:HARD], e.g. core=unlimited (repeatable; default core=0, nofile=1024:524288)")
+	installCmd.Flags().String("network", "", "Put a new container directly on a host NIC's LAN: macvlan:IFACE[:MODE] or ipvlan:IFACE[:MODE] (rootful podman)")
+	installCmd.Flags().String("ip", "", "Static IPv4 address for --network (default: DHCP, macvlan only)")
+	installCmd.Flags().String("mac-address", "", "MAC address for --network macvlan: auto (default, stable per container), random, or an address")
+	installCmd.Flags().StringArray("sysctl", nil, "Set a namespaced kernel parameter in a new container, KEY=VALUE, e.g. net.core.somaxconn=4096 (repeatable)")
+	installCmd.Flags().StringArray("cgroup-conf", nil, "Write VALUE to a cgroup v2 file of a new container, KEY=VALUE, e.g. cpu.idle=1 (repeatable)")
+	installCmd.Flags().String("proc-opts", "", "Options for a new container's /proc mount: hidepid=, gid=, subset=pid (comma-separated)")
+	installCmd.Flags().String("umask", "", "File creation mask for a new container's processes, in octal, e.g. 0027 (default 0022)")
+	installCmd.Flags().Bool("oom-kill-disable", false, "Never let the kernel's OOM killer kill a new container's processes (needs --i-understand-oom-risk)")
+	installCmd.Flags().Bool("i-understand-oom-risk", false, "Confirm --oom-kill-disable, accepting that the host may hang if the container exhausts memory")
+	installCmd.Flags().StringArray("mask-path", nil, "Hide this path inside a new container (repeatable), on top of podman's default masks")
+	installCmd.Flags().Bool("no-mask-paths", false, "Don't apply podman's default masks over /proc/kcore, /proc/keys, /sys/firmware, ... in a new container")
+	installCmd.Flags().StringArray("security-opt", nil, "Docker-compatible security option; only systempaths=unconfined (same as --no-mask-paths)")
+	installCmd.Flags().Bool("device-kvm", false, "Share /dev/kvm (plus /dev/vhost-net, /dev/net/tun) with a new container, for running VMs inside it")
 
 	removeCmd := &cobra.Command{
 		Use:   "remove <pkg>",
@@ -86,9 +341,45 @@ func main() {
 		Short: "Run an arbitrary command inside a package's container",
 		Args:  cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			src.HandleExec(args[0], args[1:])
+			stdio := src.DefaultExecStdio()
+			if cmd.Flags().Changed("interactive") || cmd.Flags().Changed("tty") {
+				stdio.Interactive, _ = cmd.Flags().GetBool("interactive")
+				stdio.TTY, _ = cmd.Flags().GetBool("tty")
+			}
+			if cmd.Flags().Changed("detach-keys") {
+				stdio.DetachKeys, _ = cmd.Flags().GetString("detach-keys")
+			}
+			var opts src.ExecOptions
+			opts.User, _ = cmd.Flags().GetString("user")
+			opts.Env, _ = cmd.Flags().GetStringArray("env")
+			opts.Workdir, _ = cmd.Flags().GetString("workdir")
+			opts.Privileged, _ = cmd.Flags().GetBool("privileged")
+			os.Exit(src.HandleExec(args[0], args[1:], stdio, opts))
+		},
+	}
+	execCmd.Flags().BoolP("interactive", "i", false, "Forward stdin to the command (default: on only when run from a terminal)")
+	execCmd.Flags().BoolP("tty", "t", false, "Allocate a pseudo-terminal inside the container (default: on only when run from a terminal)")
+	execCmd.Flags().StringP("user", "u", "", "Run the command as this user (name or uid, optionally :group) inside the container")
+	execCmd.Flags().StringArrayP("env", "e", nil, "Set NAME=VALUE for the command, or forward just NAME's host value (repeatable)")
+	execCmd.Flags().StringP("workdir", "w", "", "Working directory for the command inside the container")
+	execCmd.Flags().Bool("privileged", false, "Give the command extended privileges, for debugging the container's restrictions")
+	execCmd.Flags().String("detach-keys", "", "Key sequence that detaches from an interactive session, e.g. \"ctrl-x,ctrl-d\"; empty disables (default: config.hk, else ctrl-p,ctrl-q)")
+
+	shellCmd := &cobra.Command{
+		Use:   "shell <pkg|container>",
+		Short: "Open an interactive shell (bash, else ash, else sh) in a package's container",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var opts src.ExecOptions
+			opts.User, _ = cmd.Flags().GetString("user")
+			opts.Env, _ = cmd.Flags().GetStringArray("env")
+			opts.Workdir, _ = cmd.Flags().GetString("workdir")
+			os.Exit(src.HandleShell(args[0], opts))
 		},
 	}
+	shellCmd.Flags().StringP("user", "u", "", "Open the shell as this user (name or uid, optionally :group)")
+	shellCmd.Flags().StringArrayP("env", "e", nil, "Set NAME=VALUE in the shell, or forward just NAME's host value (repeatable)")
+	shellCmd.Flags().StringP("workdir", "w", "", "Directory to start the shell in")
 
 	snapshotCmd := &cobra.Command{
 		Use:   "snapshot [container]",
@@ -180,18 +471,109 @@ func main() {
 		Args:  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
-			src.HandleClean(dryRun)
+			homes, _ := cmd.Flags().GetBool("homes")
+			src.HandleClean(dryRun, homes)
 		},
 	}
 	cleanCmd.Flags().Bool("dry-run", false, "Show what would be cleaned without doing it")
+	cleanCmd.Flags().Bool("homes", false, "Also delete isolated home directories that no installed package uses")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show container status dashboard",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			watch, _ := cmd.Flags().GetBool("watch")
+			filters, _ := cmd.Flags().GetStringArray("filter")
+			src.HandleStatus(watch, filters)
+		},
+	}
+	statusCmd.Flags().BoolP("watch", "w", false, "Keep the dashboard open and update it as containers change")
+	statusCmd.Flags().StringArray("filter", nil, "Only show containers with this annotation: annotation=KEY or annotation=KEY=VALUE (repeatable; all must match)")
+
+	tagsCmd := &cobra.Command{
+		Use:   "tags <image>",
+		Short: "List the tags of an image repository on its registry",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			limit, _ := cmd.Flags().GetInt("limit")
+			format, _ := cmd.Flags().GetString("format")
+			src.HandleTags(args[0], limit, format)
+		},
+	}
+	tagsCmd.Flags().Int("limit", 0, "Show only the N newest tags")
+	tagsCmd.Flags().String("format", "", "Output format: json")
+
+	diffCmd := &cobra.Command{
+		Use:   "diff <container> [path]",
+		Short: "Show which files a container added, changed or deleted",
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			subtree := ""
+			if len(args) == 2 {
+				subtree = args[1]
+			}
+			format, _ := cmd.Flags().GetString("format")
+			src.HandleDiff(args[0], subtree, format)
+		},
+	}
+	diffCmd.Flags().String("format", "", "Output format: json")
+
+	chownCmd := &cobra.Command{
+		Use:   "chown <rootfs>",
+		Short: "Shift a root filesystem tree's owners into your rootless user namespace (run with sudo)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, _ := filepath.Abs(args[0])
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			src.HandleChown(dir, dryRun)
+		},
+	}
+	chownCmd.Flags().Bool("dry-run", false, "Count the files that would change without changing them")
+
+	systemCmd := &cobra.Command{
+		Use:   "system",
+		Short: "Manage Isolator's containers and state as a whole",
+	}
+	resetCmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Remove every Isolator container, snapshot, network and state file (config.hk is kept)",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+			keepImages, _ := cmd.Flags().GetBool("keep-images")
+			homes, _ := cmd.Flags().GetBool("homes")
+			src.HandleSystemReset(force, keepImages, homes)
+		},
+	}
+	resetCmd.Flags().Bool("force", false, "Don't ask for confirmation")
+	resetCmd.Flags().Bool("keep-images", false, "Keep the images containers were created from, so reinstalling doesn't pull them again")
+	resetCmd.Flags().Bool("homes", false, "Also delete every isolated home directory")
+	systemCmd.AddCommand(resetCmd)
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect config.hk",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "profiles",
+		Short: "List install profiles and the options each one sets",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			src.HandleConfigProfiles()
+		},
+	})
 
 	rootCmd.AddCommand(
 		installCmd,
 		removeCmd,
 		execCmd,
+		shellCmd,
 		snapshotCmd,
 		rollbackCmd,
 		snapshotsCmd,
+		diffCmd,
+		chownCmd,
 		&cobra.Command{
 			Use:   "search <term>",
 			Short: "Search for a package",
@@ -216,14 +598,8 @@ func main() {
 				src.HandleList()
 			},
 		},
-		&cobra.Command{
-			Use:   "status",
-			Short: "Show container status dashboard",
-			Args:  cobra.NoArgs,
-			Run: func(cmd *cobra.Command, args []string) {
-				src.HandleStatus()
-			},
-		},
+		statusCmd,
+		tagsCmd,
 		updateCmd,
 		&cobra.Command{
 			Use:   "refresh",
@@ -236,6 +612,8 @@ func main() {
 		upgradeCmd,
 		autoremoveCmd,
 		cleanCmd,
+		systemCmd,
+		configCmd,
 		&cobra.Command{
 			Use:   "version",
 			Short: "Print the isolator version",
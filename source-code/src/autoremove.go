@@ -48,7 +48,8 @@ func HandleAutoremove(dryRun bool) {
 
 	for _, o := range orphans {
 		PrintStep("Removing " + o + "...")
-		if ExecCommand(podmanBin, []string{"rm", "--force", o}) {
+		if ExecCommand(podmanBin, []string{"rm", "--force", "--volumes", o}) {
+			ForgetContainerOptions(o)
 			PrintSuccess("Removed " + o)
 		} else {
 			PrintError("Failed to remove " + o)
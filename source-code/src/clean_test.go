@@ -0,0 +1,65 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOrphanedHomes(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"firefox", "gimp", "steam"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	installed := []InstalledPackage{
+		{Pkg: "firefox", Isolated: true},
+		{Pkg: "gimp", Isolated: false},
+	}
+
+	got := orphanedHomes(root, installed)
+	want := []string{filepath.Join(root, "gimp"), filepath.Join(root, "steam")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("orphanedHomes = %v, want %v", got, want)
+	}
+	if got := orphanedHomes(filepath.Join(root, "missing"), nil); got != nil {
+		t.Fatalf("expected no orphans for a missing homes dir, got %v", got)
+	}
+}
+
+func TestResetConfigDir(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	for _, name := range []string{"config.hk", "installed.hk", "containers.hk"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "keep"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "machine-ids")); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := resetConfigDir(dir, resetKeep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"containers.hk", "installed.hk", "machine-ids"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("resetConfigDir removed %v, want %v", removed, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.hk")); err != nil {
+		t.Errorf("config.hk should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "keep")); err != nil {
+		t.Errorf("a symlink in the config dir took its target along: %v", err)
+	}
+	if removed, err := resetConfigDir(filepath.Join(dir, "missing"), resetKeep); err != nil || removed != nil {
+		t.Errorf("missing dir: got %v, %v; want nothing", removed, err)
+	}
+}
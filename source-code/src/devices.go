@@ -0,0 +1,661 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Device passthrough for RunOptions' --device-* switches. GPU, display and
+// sound devices are handled by BuildGraphicsArgs based on the package
+// type; these are the opt-in ones a package only gets when asked for.
+
+// Device-cgroup rules only matter for rootful podman: rootless containers
+// can't have a device cgroup of their own, so there the bind-mounted
+// nodes' ordinary permissions (and logind's uaccess ACLs) are what decide.
+const (
+	inputCgroupRule  = "c 13:* rwm" // evdev, joystick and mouse nodes
+	uinputCgroupRule = "c 10:223 rwm"
+)
+
+// kvmDevices are the nodes a VM inside the container needs: KVM itself,
+// plus vhost-net and tun for fast virtio networking.
+var kvmDevices = []struct{ path, cgroupRule string }{
+	{"/dev/kvm", "c 10:232 rwm"},
+	{"/dev/vhost-net", "c 10:238 rwm"},
+	{"/dev/net/tun", "c 10:200 rwm"},
+}
+
+// buildDeviceArgs returns the `podman run` arguments for the requested
+// device classes, skipping any the host doesn't have.
+//
+// KVM and V4L2 nodes are usually root:kvm / root:video 0660, and under
+// --userns=keep-id those host groups have no name or number inside the
+// container, so group access would be lost. --group-add keep-groups keeps
+// the user's host supplementary groups on the container process, so the
+// node's group permission still applies without chmod-ing or ACL-ing a
+// device the host shares with other users.
+func buildDeviceArgs(opts RunOptions) []string {
+	var args []string
+	var groupOwned bool
+	if opts.DeviceInput {
+		args = append(args, buildInputDeviceArgs()...)
+	}
+	if opts.DeviceKVM {
+		kvm := buildKVMDeviceArgs()
+		groupOwned = groupOwned || len(kvm) > 0
+		args = append(args, kvm...)
+	}
+	if opts.DeviceVideo {
+		video := buildVideoDeviceArgs()
+		groupOwned = groupOwned || len(video) > 0
+		args = append(args, video...)
+	}
+	if opts.PrivilegedDevices {
+		hw := buildPrivilegedDeviceArgs(privilegedDevicePatterns)
+		groupOwned = groupOwned || len(hw) > 0
+		args = append(args, hw...)
+	}
+	if groupOwned && os.Geteuid() != 0 {
+		args = append(args, "--group-add", "keep-groups")
+	}
+	if opts.DeviceFUSE {
+		args = append(args, buildFUSEDeviceArgs()...)
+	}
+	args = append(args, buildUSBDeviceArgs(opts.USB)...)
+	for _, rule := range opts.DeviceCgroupRules {
+		args = append(args, buildCgroupRuleArgs("/sys/dev", rule)...)
+	}
+	return dedupeDeviceArgs(args)
+}
+
+// validateGroupAdd checks --group-add. Only keep-groups is offered:
+// groups that exist inside the image are better given to the user there
+// (usermod -aG), where they last across every way into the container.
+func validateGroupAdd(mode string) error {
+	if mode != "keep-groups" {
+		return fmt.Errorf("--group-add %q is not supported (expected 'keep-groups'; add groups from the image with usermod inside the container)", mode)
+	}
+	return nil
+}
+
+// buildGroupAddArgs keeps the user's host supplementary groups on the
+// container process, e.g. docker for a bind-mounted /var/run/docker.sock.
+// Under --userns=keep-id they aren't mapped into the container, so id(1)
+// shows them as nogroup, but the kernel still checks the host GIDs on
+// bind-mounted sockets and devices. Rootful containers have no host
+// groups to keep: their process gets the groups /etc/group in the image
+// gives the user.
+func buildGroupAddArgs(mode string) []string {
+	if mode == "" || os.Geteuid() == 0 {
+		return nil
+	}
+	return []string{"--group-add", mode}
+}
+
+// dedupeGroupAddArgs keeps only the first "--group-add keep-groups": the
+// device, GPU and audio options and --group-add can each ask for it, and
+// podman refuses keep-groups alongside any other --group-add, a repeat
+// included.
+func dedupeGroupAddArgs(args []string) []string {
+	out := make([]string, 0, len(args))
+	var kept bool
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--group-add" && i+1 < len(args) && args[i+1] == "keep-groups" {
+			if kept {
+				i++
+				continue
+			}
+			kept = true
+		}
+		out = append(out, args[i])
+	}
+	return out
+}
+
+// dedupeDeviceArgs drops repeated "--device NODE" pairs — several options
+// can reach the same node (say --device-video and --privileged-devices), and
+// rootless podman bind-mounts devices, where a duplicate destination is an
+// error. A node inside a directory already passed whole (the GPU's
+// /dev/dri) counts as a repeat too.
+func dedupeDeviceArgs(args []string) []string {
+	out := make([]string, 0, len(args))
+	seen := map[string]bool{}
+	covered := func(node string) bool {
+		for p := node; p != "/" && p != "."; p = filepath.Dir(p) {
+			if seen[p] {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--device" && i+1 < len(args) {
+			node, _, _ := strings.Cut(args[i+1], ":")
+			if covered(node) {
+				i++
+				continue
+			}
+			seen[node] = true
+		}
+		out = append(out, args[i])
+	}
+	return out
+}
+
+// buildInputDeviceArgs shares /dev/input as a directory bind mount rather
+// than a --device per node, so controllers plugged in after the container
+// started show up inside it too. /run/udev/data carries the udev
+// properties (ID_INPUT_JOYSTICK and friends) SDL and Steam use to tell a
+// gamepad from a keyboard and look up its name.
+func buildInputDeviceArgs() []string {
+	var args []string
+	rootful := os.Geteuid() == 0
+	if _, err := os.Stat("/dev/input"); err == nil {
+		args = append(args, "--volume", "/dev/input:/dev/input:rw,dev")
+		if rootful {
+			args = append(args, "--device-cgroup-rule", inputCgroupRule)
+		}
+	}
+	if _, err := os.Stat("/dev/uinput"); err == nil {
+		args = append(args, "--device", "/dev/uinput")
+		if rootful {
+			args = append(args, "--device-cgroup-rule", uinputCgroupRule)
+		}
+	}
+	if _, err := os.Stat("/run/udev/data"); err == nil {
+		args = append(args, "--volume", "/run/udev/data:/run/udev/data:ro")
+	}
+	return args
+}
+
+// buildKVMDeviceArgs passes the KVM nodes through.
+func buildKVMDeviceArgs() []string {
+	var args []string
+	rootful := os.Geteuid() == 0
+	for _, d := range kvmDevices {
+		if _, err := os.Stat(d.path); err != nil {
+			continue
+		}
+		args = append(args, "--device", d.path)
+		if rootful {
+			args = append(args, "--device-cgroup-rule", d.cgroupRule)
+		}
+	}
+	return args
+}
+
+// buildVideoDeviceArgs passes every V4L2 and media-controller node
+// through. A single laptop camera commonly shows up as two or more
+// /dev/video nodes (capture plus metadata, sometimes IR), and which one
+// apps need varies, so they all go in. /sys/class/video4linux, which
+// apps read names and capabilities from, is already visible read-only
+// through the container's /sys. Only these nodes are allowed: a c 81:*
+// rule would also cover cameras attached later, which the user never
+// shared.
+func buildVideoDeviceArgs() []string {
+	var args []string
+	for _, pattern := range []string{"/dev/video*", "/dev/media*"} {
+		nodes, _ := filepath.Glob(pattern)
+		for _, n := range nodes {
+			args = append(args, "--device", n)
+		}
+	}
+	return args
+}
+
+// privilegedDevicePatterns are the hardware-access nodes
+// --privileged-devices shares: GPUs (DRM and NVIDIA's, including its
+// capability nodes), V4L2 video devices and ALSA sound devices.
+var privilegedDevicePatterns = []string{"/dev/dri/*", "/dev/nvidia*", "/dev/nvidia-caps/*", "/dev/video*", "/dev/snd/*"}
+
+// buildPrivilegedDeviceArgs passes every character device matching
+// patterns through, each with a device-cgroup rule for exactly that node
+// under rootful podman — unlike --privileged, nothing else is granted:
+// seccomp, capabilities, the masked paths and the rest of the host's /dev
+// stay as they are. Directories (/dev/dri/by-path) and anything that
+// isn't a character device are skipped.
+func buildPrivilegedDeviceArgs(patterns []string) []string {
+	var args []string
+	rootful := os.Geteuid() == 0
+	for _, pattern := range patterns {
+		nodes, _ := filepath.Glob(pattern)
+		for _, n := range nodes {
+			fi, err := os.Stat(n)
+			if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+				continue
+			}
+			args = append(args, "--device", n)
+			if st, ok := fi.Sys().(*syscall.Stat_t); ok && rootful {
+				major, minor := splitRdev(uint64(st.Rdev))
+				args = append(args, "--device-cgroup-rule", fmt.Sprintf("c %d:%d rwm", major, minor))
+			}
+		}
+	}
+	return args
+}
+
+// buildFUSEDeviceArgs lets fusermount3/fusermount (and so AppImages,
+// sshfs, rclone mount, nested fuse-overlayfs) mount inside the container.
+// Besides the device, mount(2) needs CAP_SYS_ADMIN in the container's
+// user namespace — not in podman's default set, and for rootless podman
+// only meaningful inside that namespace — and AppArmor's default
+// container profile denies mounts outright, so it's lifted when AppArmor
+// is active. Mounts are made in the container's own mount namespace with
+// podman's default private propagation, so none of them reach the host
+// and they all disappear together with the container's namespace.
+func buildFUSEDeviceArgs() []string {
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		return nil
+	}
+	args := []string{"--device", "/dev/fuse", "--cap-add=CAP_SYS_ADMIN"}
+	if os.Geteuid() == 0 {
+		args = append(args, "--device-cgroup-rule", "c 10:229 rwm")
+	}
+	if appArmorEnabled() {
+		args = append(args, "--security-opt", "apparmor=unconfined")
+	}
+	return args
+}
+
+func appArmorEnabled() bool {
+	data, err := os.ReadFile("/sys/module/apparmor/parameters/enabled")
+	return err == nil && len(data) > 0 && data[0] == 'Y'
+}
+
+// ---------------------------------------------------------------------------
+// USB passthrough (--usb vendor:product | --usb bus.device)
+// ---------------------------------------------------------------------------
+
+const usbSysfsRoot = "/sys/bus/usb/devices"
+
+var (
+	usbVendorProductRe = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{4}$`)
+	usbBusDeviceRe     = regexp.MustCompile(`^[0-9]{1,3}\.[0-9]{1,3}$`)
+)
+
+// usbDevice is one entry of /sys/bus/usb/devices, i.e. what lsusb shows.
+type usbDevice struct {
+	Bus, Dev        int
+	Vendor, Product string // lower-case hex, as in sysfs
+	Name            string // manufacturer + product strings, if any
+}
+
+func (d usbDevice) node() string {
+	return fmt.Sprintf("/dev/bus/usb/%03d/%03d", d.Bus, d.Dev)
+}
+
+func (d usbDevice) String() string {
+	s := fmt.Sprintf("%s:%s  bus %03d device %03d", d.Vendor, d.Product, d.Bus, d.Dev)
+	if d.Name != "" {
+		s += "  " + d.Name
+	}
+	return s
+}
+
+// validateUSBSpec checks a --usb value's syntax; whether the device is
+// actually plugged in is up to resolveUSB.
+func validateUSBSpec(spec string) error {
+	if usbVendorProductRe.MatchString(spec) || usbBusDeviceRe.MatchString(spec) {
+		return nil
+	}
+	return fmt.Errorf("%q should be vendor:product (e.g. 0483:df11) or bus.device (e.g. 1.7), as shown by lsusb", spec)
+}
+
+// listUSBDevices reads every USB device (not interface) under sysRoot.
+func listUSBDevices(sysRoot string) []usbDevice {
+	entries, err := os.ReadDir(sysRoot)
+	if err != nil {
+		return nil
+	}
+	read := func(dir, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return strings.TrimSpace(string(data))
+	}
+	var devices []usbDevice
+	for _, e := range entries {
+		dir := filepath.Join(sysRoot, e.Name())
+		vendor := read(dir, "idVendor")
+		if vendor == "" {
+			continue // an interface (1-1:1.0), not a device
+		}
+		bus, err1 := strconv.Atoi(read(dir, "busnum"))
+		dev, err2 := strconv.Atoi(read(dir, "devnum"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		devices = append(devices, usbDevice{
+			Bus:     bus,
+			Dev:     dev,
+			Vendor:  strings.ToLower(vendor),
+			Product: strings.ToLower(read(dir, "idProduct")),
+			Name:    strings.TrimSpace(read(dir, "manufacturer") + " " + read(dir, "product")),
+		})
+	}
+	return devices
+}
+
+// resolveUSB finds the devices spec refers to: every attached device with
+// that vendor:product (two identical adapters are both passed through),
+// or the one device at bus.device. The error lists what is attached, so
+// a typo or an unplugged device is easy to spot.
+func resolveUSB(sysRoot, spec string) ([]usbDevice, error) {
+	all := listUSBDevices(sysRoot)
+	var found []usbDevice
+	for _, d := range all {
+		if usbVendorProductRe.MatchString(spec) {
+			if strings.EqualFold(spec, d.Vendor+":"+d.Product) {
+				found = append(found, d)
+			}
+		} else if busStr, devStr, ok := strings.Cut(spec, "."); ok {
+			bus, _ := strconv.Atoi(busStr)
+			dev, _ := strconv.Atoi(devStr)
+			if d.Bus == bus && d.Dev == dev {
+				found = append(found, d)
+			}
+		}
+	}
+	if len(found) > 0 {
+		return found, nil
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("USB device %s not found (no USB devices visible in %s)", spec, sysRoot)
+	}
+	lines := make([]string, len(all))
+	for i, d := range all {
+		lines[i] = "  " + d.String()
+	}
+	return nil, fmt.Errorf("USB device %s not found, available devices are:\n%s", spec, strings.Join(lines, "\n"))
+}
+
+// buildUSBDeviceArgs passes each resolved /dev/bus/usb node through. The
+// node is fixed when the container is created: podman can't add devices
+// to a running container, so a device that re-enumerates (as most do
+// when they reset into a bootloader to be flashed) comes back with a new
+// device number the container doesn't have, and the container has to be
+// recreated with the device in the mode it'll be used in. Each --device
+// brings its own device-cgroup rule; a c 189:* rule on top would let a
+// rootful container, which keeps CAP_MKNOD, open every USB device on the
+// host.
+func buildUSBDeviceArgs(specs []string) []string {
+	var args []string
+	for _, spec := range specs {
+		devices, err := resolveUSB(usbSysfsRoot, spec)
+		if err != nil {
+			continue
+		}
+		for _, d := range devices {
+			args = append(args, "--device", d.node())
+		}
+	}
+	return args
+}
+
+// CheckDevices resolves the options that name specific host devices and
+// fails if one isn't attached — passing through nothing while the user
+// expects their programmer or dongle would only fail later, and less
+// clearly, inside the container.
+func (o RunOptions) CheckDevices() error {
+	for _, spec := range o.USB {
+		if _, err := resolveUSB(usbSysfsRoot, spec); err != nil {
+			return err
+		}
+	}
+	if o.MemoryNodes != "" {
+		if err := checkNodesOnline(numaSysfsRoot, o.MemoryNodes); err != nil {
+			return err
+		}
+	}
+	if o.Network != "" {
+		if err := checkNetworkHost(o.Network, o.IP); err != nil {
+			return err
+		}
+	}
+	if len(o.CgroupConf) > 0 {
+		if err := checkCgroupConfHost(); err != nil {
+			return err
+		}
+	}
+	if o.OOMKillDisable && hostCgroupV2() && os.Geteuid() != 0 {
+		return fmt.Errorf("--oom-kill-disable: rootless podman can't exempt a container from the OOM killer on cgroup v2 (that needs oom_score_adj -1000, which only root can set) — run isolator as root for this container, or leave the option out")
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// --device-cgroup-rule
+// ---------------------------------------------------------------------------
+
+var cgroupRuleRe = regexp.MustCompile(`^([abc]) ([0-9]+|\*):([0-9]+|\*) ([rwm]{1,3})$`)
+
+// validateCgroupRule checks a rule in the kernel's devices.allow syntax:
+// "c 13:* rwm" — type (a, b or c), major:minor (either may be *), access.
+func validateCgroupRule(rule string) error {
+	if !cgroupRuleRe.MatchString(rule) {
+		return fmt.Errorf("%q should look like 'c 189:* rwm' (type a|b|c, major:minor with * allowed, access from rwm)", rule)
+	}
+	return nil
+}
+
+// buildCgroupRuleArgs makes a device-cgroup rule actually usable. The rule
+// only grants permission; the node still has to exist in the container's
+// /dev, and a rootless container can't mknod one (and has no device
+// cgroup to grant anything in). So every host device the rule matches —
+// looked up in sysRoot/{char,block}/MAJOR:MINOR — is passed through with
+// --device, which creates the node with the rule's access mode, and the
+// rule itself is only added under rootful podman, where it also covers
+// matching devices that appear later.
+func buildCgroupRuleArgs(sysRoot, rule string) []string {
+	m := cgroupRuleRe.FindStringSubmatch(rule)
+	if m == nil {
+		return nil
+	}
+	typ, major, minor, access := m[1], m[2], m[3], m[4]
+	var args []string
+	var kinds []string
+	if typ == "c" || typ == "a" {
+		kinds = append(kinds, "char")
+	}
+	if typ == "b" || typ == "a" {
+		kinds = append(kinds, "block")
+	}
+	for _, kind := range kinds {
+		entries, _ := os.ReadDir(filepath.Join(sysRoot, kind))
+		for _, e := range entries {
+			maj, min, ok := strings.Cut(e.Name(), ":")
+			if !ok || (major != "*" && maj != major) || (minor != "*" && min != minor) {
+				continue
+			}
+			if name := ueventDevname(filepath.Join(sysRoot, kind, e.Name(), "uevent")); name != "" {
+				node := "/dev/" + name
+				args = append(args, "--device", node+":"+node+":"+access)
+			}
+		}
+	}
+	if os.Geteuid() == 0 {
+		args = append(args, "--device-cgroup-rule", rule)
+	}
+	return args
+}
+
+// ueventDevname returns the DEVNAME= entry of a sysfs uevent file — the
+// node's path relative to /dev, e.g. "bus/usb/001/007".
+func ueventDevname(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, ok := strings.CutPrefix(line, "DEVNAME="); ok && !strings.Contains(name, "..") {
+			return name
+		}
+	}
+	return ""
+}
+
+// HostWarnings reports requested options the host can't fully satisfy.
+// They're warnings, not errors: the container still works, just without
+// whatever the host is missing.
+func (o RunOptions) HostWarnings() []string {
+	var warnings []string
+	if o.DeviceInput {
+		warnings = append(warnings, inputAccessWarnings()...)
+	}
+	if o.DeviceKVM {
+		warnings = append(warnings, kvmAccessWarnings(o.Runtime)...)
+	}
+	if o.DeviceVideo {
+		nodes, _ := filepath.Glob("/dev/video*")
+		if len(nodes) == 0 {
+			warnings = append(warnings, "--device-video: no /dev/video* devices on this host — is the camera connected and its driver loaded?")
+		} else if syscall.Access(nodes[0], 6 /* R_OK|W_OK */) != nil {
+			warnings = append(warnings, "--device-video: your user can't open "+nodes[0]+" — add it to the 'video' group (then log in again)")
+		}
+	}
+	if o.PrivilegedDevices && len(buildPrivilegedDeviceArgs(privilegedDevicePatterns)) == 0 {
+		warnings = append(warnings, "--privileged-devices: no GPU, video or sound devices on this host (/dev/dri, /dev/nvidia*, /dev/video*, /dev/snd), so none are shared")
+	}
+	if o.CgroupNS == "private" {
+		if !hostCgroupNamespaces() {
+			warnings = append(warnings, "--cgroupns=private: this kernel has no cgroup namespaces (added in Linux 4.6), so --cgroupns=private is ignored and the container shares the host's cgroup hierarchy")
+		} else if !hostCgroupV2() {
+			warnings = append(warnings, "--cgroupns=private: this host uses cgroup v1, so /sys/fs/cgroup in the container isn't a cgroup2 mount rooted at its own subtree")
+		}
+	}
+	if len(o.DeviceReadIOPS) > 0 || len(o.DeviceWriteIOPS) > 0 {
+		warnings = append(warnings, controllerDelegationWarning("--device-read-iops/--device-write-iops", "io")...)
+	}
+	if o.CPUShares != 0 || o.CPUWeight != 0 {
+		warnings = append(warnings, controllerDelegationWarning("--cpu-shares/--cpu-weight", "cpu")...)
+	}
+	if o.MemoryNodes != "" {
+		warnings = append(warnings, controllerDelegationWarning("--memory-node-pin", "cpuset")...)
+	}
+	if len(o.CgroupConf) > 0 && os.Geteuid() != 0 {
+		if why := rootlessScopeProblem(); why != "" {
+			warnings = append(warnings, "--cgroup-conf: "+why)
+		}
+	}
+	if strings.Contains(o.ProcOpts, "subset=") && !procSubsetSupported() {
+		warnings = append(warnings, "--proc-opts: this kernel has no subset=pid (added in Linux 5.8), so /proc is mounted without it; the other options still apply")
+	}
+	if o.StorageSize != "" {
+		if ok, why := storageQuotaSupport(); !ok {
+			warnings = append(warnings, "--storage-size: "+why+" — the container will be created without a size limit")
+		}
+	}
+	if o.OOMKillDisable {
+		warnings = append(warnings, "--oom-kill-disable: if this container uses up the host's memory, the kernel can't reclaim it by killing the container's processes and the whole machine may hang — set a memory limit inside the app (e.g. the database's buffer pool) to match")
+	}
+	if o.RootFS != "" && os.Geteuid() != 0 {
+		if w := rootfsOwnerWarning(o.RootFS); w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+	if o.GroupAdd != "" {
+		if os.Geteuid() == 0 {
+			warnings = append(warnings, "--group-add keep-groups: rootful containers have no host groups to keep — the container's process gets the groups its image gives the user")
+		} else if o.Runtime != "" && filepath.Base(o.Runtime) != "crun" {
+			warnings = append(warnings, "--group-add keep-groups: only the crun runtime can keep your host groups; "+filepath.Base(o.Runtime)+" will refuse to start the container")
+		}
+	}
+	if o.DeviceFUSE {
+		if _, err := os.Stat("/dev/fuse"); err != nil {
+			warnings = append(warnings, "--device-fuse: this host has no /dev/fuse (try 'modprobe fuse') — FUSE mounts won't work in the container")
+		} else if os.Geteuid() == 0 {
+			warnings = append(warnings, "--device-fuse: with rootful podman this grants the container real CAP_SYS_ADMIN; prefer running isolator as your user")
+		}
+	}
+	return warnings
+}
+
+// hostCgroupV2 reports whether the host runs the unified cgroup v2
+// hierarchy.
+func hostCgroupV2() bool {
+	_, err := os.Stat("/sys/fs/cgroup/cgroup.controllers")
+	return err == nil
+}
+
+// hostCgroupNamespaces reports whether the kernel can create cgroup
+// namespaces (CLONE_NEWCGROUP, Linux 4.6+). The namespace file is checked
+// rather than the kernel version, so backports and kernels built without
+// the namespace are judged correctly too.
+func hostCgroupNamespaces() bool {
+	_, err := os.Stat("/proc/self/ns/cgroup")
+	return err == nil
+}
+
+// effectiveCgroupNS resolves --cgroupns for this host. "private" on a
+// kernel that can't create the namespace would make the runtime refuse
+// to start the container, so it's dropped in favour of "host" (with a
+// warning from HostWarnings); "auto" picks whichever of the two the
+// kernel supports.
+func effectiveCgroupNS(mode string) string {
+	switch mode {
+	case "private", "auto":
+		if hostCgroupNamespaces() {
+			return "private"
+		}
+		return "host"
+	}
+	return mode
+}
+
+// buildOOMArgs keeps the kernel's OOM killer away from the container.
+// cgroup v1 has a switch for exactly that (memory.oom_control, which
+// podman's --oom-kill-disable sets); v2 has none — memory.oom.group only
+// decides whether a kill takes the whole cgroup — so there every process
+// gets the OOM score adjustment that exempts it instead. Lowering that
+// score needs real privilege, so CheckDevices refuses the option on
+// rootless v2 hosts.
+func buildOOMArgs(disable bool) []string {
+	switch {
+	case !disable:
+		return nil
+	case !hostCgroupV2():
+		return []string{"--oom-kill-disable"}
+	case os.Geteuid() == 0:
+		return []string{"--oom-score-adj=-1000"}
+	}
+	return nil
+}
+
+// inputAccessWarnings checks whether the invoking user can actually open
+// the input devices being shared. That's either through membership in the
+// "input" group or through the per-device ACLs logind grants the active
+// seat's user (the usual way gamepads become accessible).
+func inputAccessWarnings() []string {
+	events, _ := filepath.Glob("/dev/input/event*")
+	if len(events) == 0 {
+		if _, err := os.Stat("/dev/input"); err != nil {
+			return []string{"--device-input: this host has no /dev/input — no input devices will be shared"}
+		}
+		return nil
+	}
+	for _, ev := range events {
+		if syscall.Access(ev, 4 /* R_OK */) == nil {
+			return nil
+		}
+	}
+	return []string{"--device-input: your user can't read any /dev/input/event* device — add it to the 'input' group (then log in again) or plug the controller in on the active seat"}
+}
+
+// kvmAccessWarnings flags a host without KVM, a user who can't open it,
+// and runtimes that can't honour keep-groups (only crun implements it).
+func kvmAccessWarnings(runtime string) []string {
+	if _, err := os.Stat("/dev/kvm"); err != nil {
+		return []string{"--device-kvm: this host has no /dev/kvm (is virtualization enabled and the kvm module loaded?) — VMs in the container will fall back to slow emulation"}
+	}
+	var warnings []string
+	if syscall.Access("/dev/kvm", 6 /* R_OK|W_OK */) != nil {
+		warnings = append(warnings, "--device-kvm: your user can't open /dev/kvm — add it to the 'kvm' group (then log in again)")
+	}
+	if runtime != "" && filepath.Base(runtime) != "crun" {
+		warnings = append(warnings, "--device-kvm: keeping your kvm group inside the container needs the crun runtime; with "+filepath.Base(runtime)+" /dev/kvm only works if it's world-accessible")
+	}
+	return warnings
+}
@@ -131,7 +131,7 @@ func HandleEnvFile(path string) {
 	firstBuild := !ContainerExists(contName)
 	if firstBuild {
 		PrintStep("Creating environment container (bind-mounted to your project dir)...")
-		if !CreateContainer(contName, d.Image, spec.ProjectDir, "cli", d.InitSystem) {
+		if !CreateContainer(contName, d.Image, spec.ProjectDir, "cli", d.InitSystem, RunOptionsFromConfig(LoadConfig())) {
 			PrintError(fmt.Sprintf("Failed to create environment container '%s'", contName))
 			return
 		}
@@ -174,6 +174,8 @@ type graphicsContext struct {
 	cfg        Config
 	pkgType    string // "cli" | "gui" | "de" | "lib" | "system"
 	initSystem string // "systemd" | "sysvinit" | "none" — see Distro.InitSystem
+	image      string
+	opts       RunOptions
 }
 
 // BuildGraphicsArgs returns the extra `podman run` arguments needed to give
@@ -327,9 +329,12 @@ func buildFontsAndThemeArgs(ctx graphicsContext) []string {
 	var args []string
 	home := os.Getenv("HOME")
 
-	if _, err := os.Stat("/usr/share/fonts"); err == nil {
-		args = append(args, "--volume", "/usr/share/fonts:/usr/share/fonts:ro")
+	var env []string
+	if ctx.opts.RootFS == "" {
+		// A --rootfs tree has no image config to read.
+		env = imageEnv(ctx.image)
 	}
+	args = append(args, buildHostAssetArgs("/", env)...)
 	userFonts := filepath.Join(home, ".fonts")
 	if _, err := os.Stat(userFonts); err == nil {
 		args = append(args, "--volume", userFonts+":/home/user/.fonts:ro")
@@ -362,18 +367,145 @@ func buildFontsAndThemeArgs(ctx graphicsContext) []string {
 	return args
 }
 
-func buildMiscDesktopArgs(ctx graphicsContext) []string {
+// Host font, icon and cursor directories are mounted under /run/host —
+// the same place toolbox and flatpak put them — rather than over the
+// image's own /usr/share/fonts or /usr/share/icons, so anything the image
+// ships stays visible and the host's copies are only added alongside.
+const hostAssetsRoot = "/run/host"
+
+// hostFontconfigDropIn makes fontconfig inside the container scan the host
+// fonts and reuse the host's prebuilt caches: <remap-dir> tells it to
+// treat /run/host/fonts as /usr/share/fonts when looking up cache files,
+// so fc-cache has nothing to rebuild. <dir> and <cachedir> entries that
+// don't exist (no host fonts, no system cache) are simply skipped.
+const hostFontconfigDropIn = `<?xml version="1.0"?>
+<!DOCTYPE fontconfig SYSTEM "urn:fontconfig:fonts.dtd">
+<!-- Generated by Isolator: host fonts, mounted read-only by the container. -->
+<fontconfig>
+  <remap-dir as-path="/usr/share/fonts">/run/host/fonts</remap-dir>
+  <remap-dir as-path="/usr/local/share/fonts">/run/host/local-fonts</remap-dir>
+  <cachedir>/run/host/fontconfig-cache</cachedir>
+</fontconfig>
+`
+
+// buildHostAssetArgs mounts the host's system fonts, icon themes (which
+// also hold the cursor themes) and fontconfig cache, found under root,
+// read-only, skipping whichever of them the host doesn't have. User fonts
+// and icons under ~/.local/share and ~/.fonts come along with the home
+// directory. imageEnv is the image's own environment, whose search paths
+// the host's are appended to.
+func buildHostAssetArgs(root string, imageEnv []string) []string {
 	var args []string
+	mounts := []struct{ host, dest string }{
+		{"/usr/share/fonts", hostAssetsRoot + "/fonts"},
+		{"/usr/local/share/fonts", hostAssetsRoot + "/local-fonts"},
+		{"/var/cache/fontconfig", hostAssetsRoot + "/fontconfig-cache"},
+		{"/usr/share/icons", hostAssetsRoot + "/share/icons"},
+	}
+	var haveFonts, haveIcons bool
+	for _, m := range mounts {
+		src := filepath.Join(root, m.host)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		args = append(args, "--volume", src+":"+m.dest+":ro")
+		switch m.host {
+		case "/usr/share/fonts", "/usr/local/share/fonts":
+			haveFonts = true
+		case "/usr/share/icons":
+			haveIcons = true
+		}
+	}
 
-	if _, err := os.Stat("/etc/localtime"); err == nil {
-		args = append(args, "--volume", "/etc/localtime:/etc/localtime:ro")
+	if haveFonts {
+		if dropIn := writeFontconfigDropIn(); dropIn != "" {
+			args = append(args, "--volume", dropIn+":/etc/fonts/conf.d/99-isolator-host-fonts.conf:ro")
+		}
+	}
+	if haveIcons {
+		// Appended after the image's own data dirs and cursor paths (or,
+		// if it sets none, the XDG and libXcursor defaults), so the
+		// image's themes win whenever both have one by the same name.
+		dataDirs := envValue(imageEnv, "XDG_DATA_DIRS", "/usr/local/share:/usr/share")
+		cursorPath := envValue(imageEnv, "XCURSOR_PATH", "~/.local/share/icons:~/.icons:/usr/share/icons:/usr/share/pixmaps")
+		args = append(args,
+			"--env", "XDG_DATA_DIRS="+appendPathList(dataDirs, hostAssetsRoot+"/share"),
+			"--env", "XCURSOR_PATH="+appendPathList(cursorPath, hostAssetsRoot+"/share/icons"),
+		)
+	}
+	for _, name := range []string{"XCURSOR_THEME", "XCURSOR_SIZE"} {
+		if v := os.Getenv(name); v != "" {
+			args = append(args, "--env", name+"="+v)
+		}
 	}
-	if tz := os.Getenv("TZ"); tz != "" {
-		args = append(args, "--env", "TZ="+tz)
+	return args
+}
+
+// appendPathList adds dir to a colon-separated list that doesn't have it.
+func appendPathList(list, dir string) string {
+	if stringInSlice(dir, strings.Split(list, ":")) {
+		return list
 	}
-	for _, envVar := range []string{"LANG", "LC_ALL", "LANGUAGE"} {
-		if v := os.Getenv(envVar); v != "" {
-			args = append(args, "--env", envVar+"="+v)
+	return list + ":" + dir
+}
+
+// envValue returns name's value in env (KEY=VALUE entries), or def.
+func envValue(env []string, name, def string) string {
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, name+"="); ok && v != "" {
+			return v
+		}
+	}
+	return def
+}
+
+// imageEnv returns the environment image sets, or nil if podman can't
+// inspect it.
+func imageEnv(image string) []string {
+	out, err := exec.Command(podmanBin, "image", "inspect", "--format", "{{range .Config.Env}}{{println .}}{{end}}", image).Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+// writeFontconfigDropIn keeps the generated drop-in in Isolator's config
+// dir and returns its path, or "" if it couldn't be written (the fonts are
+// then still mounted, fontconfig just won't know to look there).
+func writeFontconfigDropIn() string {
+	if err := EnsureConfigDir(); err != nil {
+		return ""
+	}
+	path := ConfigPath("fontconfig-host-fonts.conf")
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == hostFontconfigDropIn {
+		return path
+	}
+	if err := os.WriteFile(path, []byte(hostFontconfigDropIn), 0644); err != nil {
+		return ""
+	}
+	return path
+}
+
+func buildMiscDesktopArgs(ctx graphicsContext) []string {
+	var args []string
+
+	// Desktop apps follow the host's clock and language unless the user
+	// picked explicit --tz/--locale settings, which BuildRunOptionArgs
+	// applies instead (mounting /etc/localtime here as well would clash
+	// with podman's own --tz handling).
+	if ctx.opts.Timezone == "" {
+		if _, err := os.Stat("/etc/localtime"); err == nil {
+			args = append(args, "--volume", "/etc/localtime:/etc/localtime:ro")
+		}
+		if tz := os.Getenv("TZ"); tz != "" {
+			args = append(args, "--env", "TZ="+tz)
+		}
+	}
+	if ctx.opts.Locale != "host" {
+		for _, envVar := range []string{"LANG", "LC_ALL", "LANGUAGE"} {
+			if v := os.Getenv(envVar); v != "" {
+				args = append(args, "--env", envVar+"="+v)
+			}
 		}
 	}
 
@@ -408,6 +540,16 @@ func buildSystemdArgs(ctx graphicsContext, allowed bool, kindLabel string) []str
 		PrintWarn(fmt.Sprintf("Package needs systemd/cgroup access (type '%s'), but that's disabled in config.hk — running with app-level privileges only", kindLabel))
 		return nil
 	}
+	// On cgroup v2, podman's systemd mode mounts the container's own
+	// cgroup2 subtree read-write at /sys/fs/cgroup, which is all systemd
+	// needs; binding the host's tree over it would show the container
+	// every other cgroup on the host, and make runtimes that size
+	// themselves from /sys/fs/cgroup (JVM, .NET, Go) read the host's
+	// limits instead of the container's. v1 systemd wants the host's
+	// hierarchy itself.
+	if hostCgroupV2() {
+		return []string{"--systemd", "always"}
+	}
 	return []string{
 		"--systemd", "always",
 		"--volume", "/sys/fs/cgroup:/sys/fs/cgroup:rw",
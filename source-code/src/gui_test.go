@@ -0,0 +1,65 @@
+package src
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildHostAssetArgs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XCURSOR_THEME", "")
+	t.Setenv("XCURSOR_SIZE", "")
+	dropIn := ConfigPath("fontconfig-host-fonts.conf")
+	defaultEnv := []string{
+		"--env", "XDG_DATA_DIRS=/usr/local/share:/usr/share:/run/host/share",
+		"--env", "XCURSOR_PATH=~/.local/share/icons:~/.icons:/usr/share/icons:/usr/share/pixmaps:/run/host/share/icons",
+	}
+
+	for _, c := range []struct {
+		name     string
+		host     []string // directories the stub host has
+		imageEnv []string
+		want     func(root string) []string
+	}{
+		{"nothing to share", nil, nil, func(string) []string { return nil }},
+		{"fonts only", []string{"usr/share/fonts", "var/cache/fontconfig"}, nil, func(root string) []string {
+			return []string{
+				"--volume", root + "/usr/share/fonts:/run/host/fonts:ro",
+				"--volume", root + "/var/cache/fontconfig:/run/host/fontconfig-cache:ro",
+				"--volume", dropIn + ":/etc/fonts/conf.d/99-isolator-host-fonts.conf:ro",
+			}
+		}},
+		{"icons with the XDG defaults", []string{"usr/share/icons"}, nil, func(root string) []string {
+			return append([]string{"--volume", root + "/usr/share/icons:/run/host/share/icons:ro"}, defaultEnv...)
+		}},
+		{"icons after the image's own paths", []string{"usr/share/icons"}, []string{"PATH=/usr/bin", "XDG_DATA_DIRS=/opt/app/share:/usr/share", "XCURSOR_PATH=/opt/app/icons"}, func(root string) []string {
+			return []string{
+				"--volume", root + "/usr/share/icons:/run/host/share/icons:ro",
+				"--env", "XDG_DATA_DIRS=/opt/app/share:/usr/share:/run/host/share",
+				"--env", "XCURSOR_PATH=/opt/app/icons:/run/host/share/icons",
+			}
+		}},
+		{"a snapshot image that already has them", []string{"usr/share/icons"}, []string{"XDG_DATA_DIRS=/usr/share:/run/host/share"}, func(root string) []string {
+			return []string{
+				"--volume", root + "/usr/share/icons:/run/host/share/icons:ro",
+				"--env", "XDG_DATA_DIRS=/usr/share:/run/host/share",
+				defaultEnv[2], defaultEnv[3],
+			}
+		}},
+	} {
+		root := t.TempDir()
+		for _, d := range c.host {
+			if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got, want := buildHostAssetArgs(root, c.imageEnv), c.want(root); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\n got %q\nwant %q", c.name, got, want)
+		}
+	}
+	if data, err := os.ReadFile(dropIn); err != nil || string(data) != hostFontconfigDropIn {
+		t.Errorf("fontconfig drop-in not written as expected (%v): %q", err, data)
+	}
+}
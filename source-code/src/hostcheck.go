@@ -0,0 +1,98 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// When `podman run` fails because the host can't give the container what
+// it needs, podman's own message is often only the errno: "fork/exec
+// /proc/self/exe: operation not permitted" from an AppArmor-restricted
+// user namespace, say. explainCreateFailure looks for the usual host-side
+// causes after a failed create and says what each one is and how to fix
+// it. Nothing is retried with weaker isolation: a container the user
+// asked to have its own network (or user) namespace silently getting the
+// host's is worse than one that doesn't start.
+
+// nfsSuperMagic is statfs(2)'s f_type for NFS.
+const nfsSuperMagic = 0x6969
+
+// userNSHints checks the sysctls (under procSys, normally /proc/sys) that
+// stop an unprivileged user from creating the user namespace rootless
+// podman runs every container in.
+func userNSHints(procSys string) []string {
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(procSys, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	var hints []string
+	if read("user/max_user_namespaces") == "0" {
+		hints = append(hints, "user namespaces are disabled (user.max_user_namespaces = 0), and rootless podman needs one per container — enable them with 'sudo sysctl -w user.max_user_namespaces=15000' and persist it in /etc/sysctl.d/")
+	}
+	if read("kernel/unprivileged_userns_clone") == "0" {
+		hints = append(hints, "this kernel only lets root create user namespaces (kernel.unprivileged_userns_clone = 0) — allow them with 'sudo sysctl -w kernel.unprivileged_userns_clone=1' and persist it in /etc/sysctl.d/")
+	}
+	if read("kernel/apparmor_restrict_unprivileged_userns") == "1" {
+		hints = append(hints, "AppArmor restricts unprivileged user namespaces (kernel.apparmor_restrict_unprivileged_userns = 1, the Ubuntu 24.04 default) — give podman an AppArmor profile that allows 'userns,' in /etc/apparmor.d/ (see Ubuntu's release notes), or lift the restriction with 'sudo sysctl -w kernel.apparmor_restrict_unprivileged_userns=0'")
+	}
+	return hints
+}
+
+// idMapHints checks what rootless podman needs to map more than one id
+// into the namespace: the setuid newuidmap/newgidmap helpers and a
+// subordinate range for the user.
+func idMapHints() []string {
+	var hints []string
+	for _, bin := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			hints = append(hints, bin+" isn't installed, so podman can't map the container's ids — install your distro's uidmap (Debian/Ubuntu) or shadow-utils package")
+		}
+	}
+	u, err := user.Current()
+	if err != nil {
+		return hints
+	}
+	for _, file := range []string{"/etc/subuid", "/etc/subgid"} {
+		if _, err := subordinateRange(file, u.Username, os.Getuid()); err != nil {
+			hints = append(hints, fmt.Sprintf("%s has no range for %s — add one with 'sudo usermod --add-subuids 100000-165535 --add-subgids 100000-165535 %s', then run 'podman system migrate'", file, u.Username, u.Username))
+			break
+		}
+	}
+	return hints
+}
+
+// storageHints flags overlay storage on NFS, which the kernel can't use as
+// an overlay upper layer.
+func storageHints() []string {
+	driver, root, err := podmanStore()
+	if err != nil || driver != "overlay" {
+		return nil
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil || uint32(st.Type) != nfsSuperMagic {
+		return nil
+	}
+	return []string{root + " is on NFS, which overlay storage can't use — point graphroot in ~/.config/containers/storage.conf at a local disk (rootless_storage_path does the same for every user)"}
+}
+
+// explainCreateFailure prints what about this host may have made a
+// container create fail, if anything.
+func explainCreateFailure() {
+	var hints []string
+	if os.Geteuid() != 0 {
+		hints = append(hints, userNSHints("/proc/sys")...)
+		hints = append(hints, idMapHints()...)
+	}
+	hints = append(hints, storageHints()...)
+	for _, h := range hints {
+		PrintInfo("Likely cause: " + h)
+	}
+}
@@ -50,5 +50,10 @@ func HandleInit() {
 	}
 
 	PrintGPUReport()
+	PrintProxyReport()
+	if why := lingerProblem(); why != "" {
+		fmt.Println()
+		PrintWarn(why)
+	}
 	PrintSuccess("Isolator is ready. Try: isolator search <term>")
 }
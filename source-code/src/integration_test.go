@@ -1,7 +1,10 @@
 package src
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -39,7 +42,7 @@ func TestIntegration_ContainerLifecycle(t *testing.T) {
 		t.Fatalf("failed to pull alpine:latest")
 	}
 
-	if !CreateContainer(name, "alpine:latest", "", "cli", "systemd") {
+	if !CreateContainer(name, "alpine:latest", "", "cli", "systemd", RunOptions{}) {
 		t.Fatalf("CreateContainer failed")
 	}
 
@@ -65,6 +68,7 @@ func TestIntegration_ContainerLifecycle(t *testing.T) {
 		t.Fatalf("expected 'hello-output', got %q", out)
 	}
 
+	ForgetContainerOptions(name)
 	if !ExecCommand("podman", []string{"rm", "--force", name}) {
 		t.Fatalf("failed to remove test container")
 	}
@@ -86,10 +90,160 @@ func TestNonSystemdDistroSkipsSystemdFlag(t *testing.T) {
 	}
 	defer SaveConfig(DefaultConfig())
 
-	args := getPodmanRunArgs("isolator-it-sysvinit-check", "alpine:latest", "", "system", "sysvinit")
+	args := getPodmanRunArgs("isolator-it-sysvinit-check", "alpine:latest", "", "system", "sysvinit", RunOptions{})
 	for i, a := range args {
 		if a == "--systemd" && i+1 < len(args) && args[i+1] == "always" {
 			t.Fatalf("expected no --systemd=always for a sysvinit distro, got args: %v", args)
 		}
 	}
 }
+
+// TestIntegration_DeviceKVM checks that --device-kvm leaves /dev/kvm
+// openable inside the container — the thing `qemu -accel kvm` needs; the
+// images we test with don't ship qemu itself.
+func TestIntegration_DeviceKVM(t *testing.T) {
+	requirePodman(t)
+	if kvmAccessWarnings("") != nil {
+		t.Skip("no usable /dev/kvm on this host — skipping")
+	}
+
+	name := "isolator-integration-test-kvm"
+	exec.Command("podman", "rm", "--force", name).Run()
+	defer exec.Command("podman", "rm", "--force", name).Run()
+	defer ForgetContainerOptions(name)
+
+	if !PullImage("alpine:latest") {
+		t.Fatalf("failed to pull alpine:latest")
+	}
+	if !CreateContainer(name, "alpine:latest", "", "cli", "none", RunOptions{DeviceKVM: true}) {
+		t.Fatalf("CreateContainer failed")
+	}
+	if !ExecInContainer(name, "test -c /dev/kvm && test -r /dev/kvm && test -w /dev/kvm", false, false) {
+		t.Fatalf("/dev/kvm is missing or not read/writable inside the container")
+	}
+}
+
+// requireMemoryLimits skips the test unless podman can set a memory limit
+// here: cgroup v2, and as a rootless user a delegated scope.
+func requireMemoryLimits(t *testing.T) {
+	t.Helper()
+	if !hostCgroupV2() {
+		t.Skip("cgroup v1 host — skipping")
+	}
+	if os.Geteuid() != 0 && rootlessScopeProblem() != "" {
+		t.Skip("rootless podman can't set memory limits here: " + rootlessScopeProblem())
+	}
+}
+
+// TestIntegration_CgroupMemoryLimit checks that a --cgroup-conf memory.max
+// limit is what the container's own /sys/fs/cgroup shows — where the JVM,
+// .NET and Go runtimes read their memory limit from.
+func TestIntegration_CgroupMemoryLimit(t *testing.T) {
+	requirePodman(t)
+	requireMemoryLimits(t)
+
+	name := "isolator-integration-test-memory"
+	exec.Command("podman", "rm", "--force", name).Run()
+	defer exec.Command("podman", "rm", "--force", name).Run()
+	defer ForgetContainerOptions(name)
+
+	if !PullImage("alpine:latest") {
+		t.Fatalf("failed to pull alpine:latest")
+	}
+	if !CreateContainer(name, "alpine:latest", "", "cli", "none", RunOptions{CgroupConf: []string{"memory.max=256M"}}) {
+		t.Fatalf("CreateContainer failed")
+	}
+	out, ok := ExecInContainerWithOutput(name, "cat /sys/fs/cgroup/memory.max", false)
+	if !ok || out != "268435456" {
+		t.Fatalf("/sys/fs/cgroup/memory.max = %q (ok=%v), want 268435456", out, ok)
+	}
+}
+
+// TestIntegration_SystemdCgroupLimit is the same check for a "system"
+// package, whose container runs in podman's systemd mode: /sys/fs/cgroup
+// has to be the container's own subtree there too, not the host's (whose
+// root cgroup has no memory.max at all).
+func TestIntegration_SystemdCgroupLimit(t *testing.T) {
+	requirePodman(t)
+	requireMemoryLimits(t)
+
+	// System containers have to be allowed in config.hk, which lives
+	// under $HOME. Podman's own config and storage stay where they are.
+	realHome := os.Getenv("HOME")
+	if os.Getenv("XDG_CONFIG_HOME") == "" {
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(realHome, ".config"))
+	}
+	if os.Getenv("XDG_DATA_HOME") == "" {
+		t.Setenv("XDG_DATA_HOME", filepath.Join(realHome, ".local/share"))
+	}
+	t.Setenv("HOME", t.TempDir())
+	cfg := DefaultConfig()
+	cfg.AllowSystemContainers = true
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "isolator-integration-test-systemd-memory"
+	exec.Command("podman", "rm", "--force", name).Run()
+	defer exec.Command("podman", "rm", "--force", name).Run()
+
+	if !PullImage("alpine:latest") {
+		t.Fatalf("failed to pull alpine:latest")
+	}
+	if !CreateContainer(name, "alpine:latest", "", "system", "systemd", RunOptions{CgroupConf: []string{"memory.max=256M"}}) {
+		t.Fatalf("CreateContainer failed")
+	}
+	out, ok := ExecInContainerWithOutput(name, "cat /sys/fs/cgroup/memory.max", false)
+	if !ok || out != "268435456" {
+		t.Fatalf("systemd container: /sys/fs/cgroup/memory.max = %q (ok=%v), want 268435456", out, ok)
+	}
+}
+
+// TestIntegration_DeviceVideo checks that `v4l2-ctl --list-devices` inside
+// a --device-video container sees the host's camera.
+func TestIntegration_DeviceVideo(t *testing.T) {
+	requirePodman(t)
+	if nodes, _ := filepath.Glob("/dev/video*"); len(nodes) == 0 {
+		t.Skip("no /dev/video* devices on this host — skipping")
+	}
+
+	name := "isolator-integration-test-video"
+	exec.Command("podman", "rm", "--force", name).Run()
+	defer exec.Command("podman", "rm", "--force", name).Run()
+	defer ForgetContainerOptions(name)
+
+	if !PullImage("alpine:latest") {
+		t.Fatalf("failed to pull alpine:latest")
+	}
+	if !CreateContainer(name, "alpine:latest", "", "cli", "none", RunOptions{DeviceVideo: true}) {
+		t.Fatalf("CreateContainer failed")
+	}
+	if !ExecInContainer(name, "apk add --no-cache v4l-utils >/dev/null", false, true) {
+		t.Skip("couldn't install v4l-utils in the test container (offline?) — skipping")
+	}
+	out, ok := ExecInContainerWithOutput(name, "v4l2-ctl --list-devices", false)
+	if !ok || !strings.Contains(out, "/dev/video") {
+		t.Fatalf("expected v4l2-ctl to list the host camera, got ok=%v output=%q", ok, out)
+	}
+}
+
+// TestIntegration_PingNetRaw checks that a --cap-add NET_RAW container
+// can ping as the mapped user, not just as root.
+func TestIntegration_PingNetRaw(t *testing.T) {
+	requirePodman(t)
+
+	name := "isolator-integration-test-ping"
+	exec.Command("podman", "rm", "--force", name).Run()
+	defer exec.Command("podman", "rm", "--force", name).Run()
+	defer ForgetContainerOptions(name)
+
+	if !PullImage("alpine:latest") {
+		t.Fatalf("failed to pull alpine:latest")
+	}
+	if !CreateContainer(name, "alpine:latest", "", "cli", "none", RunOptions{CapAdd: []string{"NET_RAW"}}) {
+		t.Fatalf("CreateContainer failed")
+	}
+	if !ExecInContainer(name, "/bin/ping -c 1 -W 2 127.0.0.1", false, false) {
+		t.Fatalf("ping 127.0.0.1 failed inside a NET_RAW container")
+	}
+}
@@ -0,0 +1,37 @@
+package src
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+// Containers are never children of the isolator process or the login
+// session: podman hands each one to conmon, which double-forks and
+// setsid()s away from the terminal, so closing an ssh connection (SIGHUP
+// to the session leader) doesn't reach them. What can still stop them is
+// systemd: rootless podman puts each container in a scope under
+// user@UID.service, and without lingering systemd stops that user
+// instance — and everything under it — once the user's last session ends.
+// `loginctl enable-linger` keeps the user instance (and so every
+// container and process detached with the detach keys) running.
+
+// lingerProblem returns why this user's containers would be stopped at
+// logout, or "" when they won't be (rootful podman, no systemd, or
+// lingering already on).
+func lingerProblem() string {
+	if os.Geteuid() == 0 {
+		return ""
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return ""
+	}
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join("/var/lib/systemd/linger", u.Username)); err == nil {
+		return ""
+	}
+	return "lingering is off for " + u.Username + ", so systemd stops your containers (and anything left running in them) when your last session ends — run 'loginctl enable-linger' to keep them up after logout"
+}
@@ -0,0 +1,128 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// mountSpec is one parsed --mount, in Docker's key=value,... syntax:
+//
+//	type=bind,source=/host/path,target=/container/path,readonly
+//	type=bind,src=/srv/data,dst=/data,bind-propagation=rslave,selinux=Z
+//	type=volume,source=pgdata,target=/var/lib/postgresql
+//
+// It's re-rendered for podman (see podmanArg) rather than passed through
+// verbatim, so only keys Isolator has checked ever reach podman.
+type mountSpec struct {
+	Type        string // "bind" or "volume"
+	Source      string // host path (bind) or podman volume name (volume)
+	Target      string
+	ReadOnly    bool
+	Propagation string // bind only: shared, slave, private, rshared, ...
+	SELinux     string // bind only: "z" (shared label) or "Z" (private)
+}
+
+var (
+	volumeNameRe      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	mountPropagations = []string{"shared", "slave", "private", "rshared", "rslave", "rprivate"}
+)
+
+// parseMountSpec parses and checks spec. Bind sources must already exist
+// on the host: podman would otherwise create an empty root-owned directory
+// there, which is never what a typo'd path meant.
+func parseMountSpec(spec string) (mountSpec, error) {
+	var m mountSpec
+	for _, field := range strings.Split(spec, ",") {
+		key, val, hasVal := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "type":
+			m.Type = val
+		case "source", "src":
+			m.Source = val
+		case "target", "destination", "dst":
+			m.Target = val
+		case "readonly", "ro":
+			switch {
+			case !hasVal || val == "true" || val == "1":
+				m.ReadOnly = true
+			case val == "false" || val == "0":
+				m.ReadOnly = false
+			default:
+				return m, fmt.Errorf("%s=%q should be true or false", key, val)
+			}
+		case "bind-propagation":
+			if !stringInSlice(val, mountPropagations) {
+				return m, fmt.Errorf("bind-propagation=%q should be one of %s", val, strings.Join(mountPropagations, "|"))
+			}
+			m.Propagation = val
+		case "selinux":
+			if val != "z" && val != "Z" {
+				return m, fmt.Errorf("selinux=%q should be z (label shared between containers) or Z (private to this one)", val)
+			}
+			m.SELinux = val
+		default:
+			return m, fmt.Errorf("unknown option %q (expected type, source, target, readonly, bind-propagation or selinux)", key)
+		}
+	}
+
+	if err := validateContainerPath(m.Target); err != nil {
+		return m, fmt.Errorf("target: %v", err)
+	}
+	switch m.Type {
+	case "bind":
+		if !filepath.IsAbs(m.Source) || strings.ContainsAny(m.Source, ",\n") {
+			return m, fmt.Errorf("source %q should be an absolute host path", m.Source)
+		}
+		if _, err := os.Stat(m.Source); err != nil {
+			return m, fmt.Errorf("source %q doesn't exist on this host", m.Source)
+		}
+	case "volume":
+		if !volumeNameRe.MatchString(m.Source) {
+			return m, fmt.Errorf("source %q should name a podman volume", m.Source)
+		}
+		if m.Propagation != "" || m.SELinux != "" {
+			return m, fmt.Errorf("bind-propagation and selinux only apply to type=bind")
+		}
+	case "":
+		return m, fmt.Errorf("type is required (bind or volume)")
+	default:
+		return m, fmt.Errorf("type=%q is not supported (expected bind or volume)", m.Type)
+	}
+	return m, nil
+}
+
+// podmanArg renders m for podman run --mount. Podman spells SELinux
+// relabeling relabel=shared|private in this syntax (the z/Z of -v), and
+// looks type=volume sources up as named volumes, creating them if needed.
+func (m mountSpec) podmanArg() string {
+	parts := []string{"type=" + m.Type, "source=" + m.Source, "target=" + m.Target}
+	if m.ReadOnly {
+		parts = append(parts, "readonly=true")
+	}
+	if m.Propagation != "" {
+		parts = append(parts, "bind-propagation="+m.Propagation)
+	}
+	switch m.SELinux {
+	case "z":
+		parts = append(parts, "relabel=shared")
+	case "Z":
+		parts = append(parts, "relabel=private")
+	}
+	return strings.Join(parts, ",")
+}
+
+// buildMountArgs turns validated --mount specs into podman flags.
+func buildMountArgs(specs []string) []string {
+	var args []string
+	for _, spec := range specs {
+		m, err := parseMountSpec(spec)
+		if err != nil {
+			continue
+		}
+		args = append(args, "--mount", m.podmanArg())
+	}
+	return args
+}
@@ -0,0 +1,108 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numaSysfsRoot holds the kernel's NUMA node list ("online" reads like
+// "0-1"); a machine without NUMA support has no such directory at all.
+const numaSysfsRoot = "/sys/devices/system/node"
+
+// maxNumaNodes is the kernel's largest MAX_NUMNODES (CONFIG_NODES_SHIFT
+// 10): no node number can reach it.
+const maxNumaNodes = 1024
+
+// nodeRange is one inclusive first-last entry of a node list.
+type nodeRange struct {
+	first, last int
+}
+
+func (r nodeRange) String() string {
+	if r.first == r.last {
+		return strconv.Itoa(r.first)
+	}
+	return fmt.Sprintf("%d-%d", r.first, r.last)
+}
+
+// parseNodeList parses the kernel's list syntax used by cpuset.mems (and
+// podman's --cpuset-mems): comma-separated node numbers and inclusive
+// ranges, e.g. "0", "0-1" or "0,2-3". Ranges are kept as ranges, and node
+// numbers no kernel can have are refused, so a typo like 0-9999999999
+// can't make anything walk billions of nodes.
+func parseNodeList(list string) ([]nodeRange, error) {
+	var ranges []nodeRange
+	for _, part := range strings.Split(list, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("%q should be NUMA node numbers and ranges, e.g. 0 or 0-1 or 0,2", list)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("%q should be NUMA node numbers and ranges, e.g. 0 or 0-1 or 0,2", list)
+			}
+		}
+		if last >= maxNumaNodes {
+			return nil, fmt.Errorf("%q: NUMA node numbers stop at %d", list, maxNumaNodes-1)
+		}
+		ranges = append(ranges, nodeRange{first, last})
+	}
+	return ranges, nil
+}
+
+// checkNodesOnline fails unless every node in list is online under
+// sysRoot: the kernel would refuse an offline node when the container's
+// cgroup is set up, which podman reports as an opaque write error.
+func checkNodesOnline(sysRoot, list string) error {
+	data, err := os.ReadFile(filepath.Join(sysRoot, "online"))
+	if err != nil {
+		return fmt.Errorf("--memory-node-pin: this host doesn't report any NUMA nodes (%s/online is missing)", sysRoot)
+	}
+	onlineList := strings.TrimSpace(string(data))
+	online, err := parseNodeList(onlineList)
+	if err != nil {
+		return fmt.Errorf("--memory-node-pin: can't read %s/online: %v", sysRoot, err)
+	}
+	want, err := parseNodeList(list)
+	if err != nil {
+		return fmt.Errorf("--memory-node-pin: %v", err)
+	}
+	for _, r := range want {
+		switch {
+		case rangeOnline(r, online):
+		case r.first == r.last:
+			return fmt.Errorf("--memory-node-pin: NUMA node %s isn't online (online nodes: %s)", r, onlineList)
+		default:
+			return fmt.Errorf("--memory-node-pin: NUMA nodes %s aren't all online (online nodes: %s)", r, onlineList)
+		}
+	}
+	return nil
+}
+
+// rangeOnline reports whether every node in r lies in one of online's
+// ranges, walking the ranges rather than the nodes. The kernel prints
+// online lists merged (0-3, not 0-1,2-3), but adjacent ranges are merged
+// here too in case.
+func rangeOnline(r nodeRange, online []nodeRange) bool {
+	sorted := append([]nodeRange(nil), online...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].first < sorted[j].first })
+	next := r.first
+	for _, o := range sorted {
+		if o.first > next {
+			break
+		}
+		if o.last >= next {
+			next = o.last + 1
+		}
+		if next > r.last {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package src
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// validateProcOpts checks --proc-opts against the procfs options of
+// proc(5) that make sense for a container's /proc:
+//
+//	hidepid=0|1|2|invisible|noaccess|ptraceable   whose processes are visible
+//	gid=N                                          a group exempt from hidepid
+//	subset=pid                                     only the process directories
+func validateProcOpts(opts string) error {
+	for _, opt := range strings.Split(opts, ",") {
+		name, val, _ := strings.Cut(opt, "=")
+		switch name {
+		case "hidepid":
+			if !stringInSlice(val, []string{"0", "1", "2", "invisible", "noaccess", "ptraceable"}) {
+				return fmt.Errorf("--proc-opts: hidepid=%q should be 0, 1, 2, invisible, noaccess or ptraceable", val)
+			}
+		case "gid":
+			if _, err := strconv.ParseUint(val, 10, 32); err != nil {
+				return fmt.Errorf("--proc-opts: gid=%q should be a numeric group id", val)
+			}
+		case "subset":
+			if val != "pid" {
+				return fmt.Errorf("--proc-opts: subset=%q isn't supported (the kernel only knows subset=pid)", val)
+			}
+		default:
+			return fmt.Errorf("--proc-opts: unknown option %q (expected hidepid=, gid= or subset=pid)", opt)
+		}
+	}
+	return nil
+}
+
+// procSubsetSupported reports whether the kernel accepts subset=pid,
+// which arrived with per-mount procfs options in Linux 5.8. Older kernels
+// refuse the whole mount, and with it the container.
+func procSubsetSupported() bool {
+	return kernelAtLeast(5, 8)
+}
+
+// kernelAtLeast compares the running kernel's release with major.minor.
+// An unreadable release counts as new enough, leaving the kernel to say.
+func kernelAtLeast(major, minor int) bool {
+	var u syscall.Utsname
+	if err := syscall.Uname(&u); err != nil {
+		return true
+	}
+	var b strings.Builder
+	for _, c := range u.Release {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	parts := strings.SplitN(b.String(), ".", 3)
+	if len(parts) < 2 {
+		return true
+	}
+	maj, err1 := strconv.Atoi(parts[0])
+	mnr, err2 := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return maj > major || (maj == major && mnr >= minor)
+}
+
+// effectiveProcOpts drops subset=pid where the kernel doesn't have it, so
+// the container still gets the rest (hidepid above all) rather than
+// failing to start; HostWarnings says so.
+func effectiveProcOpts(opts string) string {
+	if opts == "" || procSubsetSupported() {
+		return opts
+	}
+	var kept []string
+	for _, opt := range strings.Split(opts, ",") {
+		if !strings.HasPrefix(opt, "subset=") {
+			kept = append(kept, opt)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+// buildProcArgs passes the options for the container's /proc mount.
+// Podman's masks over /proc/kcore, /proc/keys and the rest still apply;
+// with subset=pid those files aren't there to begin with, and the runtime
+// skips masking paths that don't exist.
+func buildProcArgs(opts string) []string {
+	if opts = effectiveProcOpts(opts); opts == "" {
+		return nil
+	}
+	return []string{"--security-opt", "proc-opts=" + opts}
+}
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"os"
+	"testing"
+)
+
+func TestApplyProfileExtendsAndOverrides(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := EnsureConfigDir(); err != nil {
+		t.Fatalf("EnsureConfigDir failed: %v", err)
+	}
+	content := `[profiles]
+-> hardened
+--> cap_drop_all => true
+--> cap_add => [CHOWN, SETUID]
+-> gaming
+--> extends => hardened
+--> locale => host
+--> cap_add => [NET_RAW]
+`
+	if err := os.WriteFile(configFilePath(), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	opts, err := ApplyProfile(RunOptions{Timezone: "UTC"}, "gaming")
+	if err != nil {
+		t.Fatalf("ApplyProfile failed: %v", err)
+	}
+	want := RunOptions{Timezone: "UTC", Locale: "host", CapDropAll: true, CapAdd: []string{"NET_RAW"}}
+	if !opts.Equal(want) {
+		t.Fatalf("expected %+v, got %+v", want, opts)
+	}
+
+	if _, err := ApplyProfile(RunOptions{}, "missing"); err == nil {
+		t.Fatalf("expected an error for an undefined profile")
+	}
+}
+
+func TestValidateProfilesRejectsTyposAndLoops(t *testing.T) {
+	doc, err := ParseHK(`[profiles]
+-> a
+--> extends => b
+--> timezon => local
+-> b
+--> extends => a
+--> cap_drop_all => "yes"
+`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	problems := validateProfiles(doc.Section("profiles"))
+	for _, want := range []string{"timezon", "cap_drop_all", "loops"} {
+		found := false
+		for _, p := range problems {
+			if contains(p, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a problem mentioning %q, got %v", want, problems)
+		}
+	}
+}
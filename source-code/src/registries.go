@@ -0,0 +1,259 @@
+package src
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Podman resolves registries from containers-registries.conf(5), so the
+// [registries] section of config.hk is translated into a drop-in for it
+// instead of being re-implemented here:
+//
+//	[registries]
+//	-> search  => [mirror.corp:5000/internal, quay.io]
+//	-> blocked => [ghcr.io]
+//	-> insecure => [registry.lan:5000]
+//	-> mirrors
+//	--> "docker.io" => [mirror.corp:5000, mirror2.corp:5000]
+//	-> ca_files
+//	--> "registry.example.com" => /etc/ssl/my-ca.crt
+//
+// search is the unqualified-search list short names like "wolfi-base" are
+// tried against, in order. Each mirrors entry lists mirrors of an upstream
+// registry, tried in order before falling back to the upstream itself.
+// blocked registries can't be pulled from at all. insecure registries are
+// reached without verifying their TLS certificate (or over plain HTTP);
+// every other registry's certificate is checked against the system CA
+// bundle, plus the registry's entry in ca_files if it has one.
+//
+// The drop-in lives in the user's registries.conf.d, which podman reads
+// on top of the system configuration, so it applies to plain `podman
+// pull` as well — mirrors and blocks are usually site policy, not
+// something only Isolator should follow.
+
+// registriesDropInPath is where the generated drop-in is written.
+func registriesDropInPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config/containers/registries.conf.d/50-isolator.conf")
+}
+
+// hasRegistryConfig reports whether [registries] sets anything at all.
+func hasRegistryConfig(cfg Config) bool {
+	return len(cfg.RegistrySearch) > 0 || len(cfg.RegistryBlocked) > 0 || len(cfg.RegistryMirrors) > 0 || len(cfg.RegistryInsecure) > 0
+}
+
+// mirroredRegistries lists the upstreams [registries] -> mirrors covers,
+// sorted.
+func mirroredRegistries(cfg Config) []string {
+	names := make([]string, 0, len(cfg.RegistryMirrors))
+	for r := range cfg.RegistryMirrors {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderRegistriesConf turns the [registries] settings into TOML for
+// registries.conf.d. Registries are written in sorted order so the file
+// only changes when the settings do.
+func renderRegistriesConf(cfg Config) string {
+	var b strings.Builder
+	b.WriteString("# Generated by isolator from the [registries] section of config.hk.\n")
+	b.WriteString("# Edit that instead: this file is rewritten before each pull.\n")
+	if len(cfg.RegistrySearch) > 0 {
+		fmt.Fprintf(&b, "\nunqualified-search-registries = %s\n", tomlStrings(cfg.RegistrySearch))
+	}
+
+	names := mirroredRegistries(cfg)
+	for _, r := range append(append([]string{}, cfg.RegistryBlocked...), cfg.RegistryInsecure...) {
+		if !stringInSlice(r, names) {
+			names = append(names, r)
+		}
+	}
+	sort.Strings(names)
+	for _, r := range names {
+		fmt.Fprintf(&b, "\n[[registry]]\nprefix = %s\nlocation = %s\n", strconv.Quote(r), strconv.Quote(r))
+		if stringInSlice(r, cfg.RegistryBlocked) {
+			b.WriteString("blocked = true\n")
+		}
+		if stringInSlice(r, cfg.RegistryInsecure) {
+			b.WriteString("insecure = true\n")
+		}
+		for _, m := range cfg.RegistryMirrors[r] {
+			fmt.Fprintf(&b, "\n[[registry.mirror]]\nlocation = %s\n", strconv.Quote(m))
+		}
+	}
+	return b.String()
+}
+
+func tomlStrings(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = strconv.Quote(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// SyncRegistriesConf writes the drop-in when [registries] has settings
+// and removes it when it no longer does. The file is left untouched if it
+// already says the same thing.
+func SyncRegistriesConf(cfg Config) error {
+	path := registriesDropInPath()
+	if !hasRegistryConfig(cfg) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	want := []byte(renderRegistriesConf(cfg))
+	if have, err := os.ReadFile(path); err == nil && bytes.Equal(have, want) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, want, 0644)
+}
+
+// Podman has no setting naming a CA file; it trusts the *.crt files in
+// containers-certs.d(5)'s directory for each registry, on top of the
+// system bundle. So each [registries] -> ca_files entry is copied there,
+// under a name of Isolator's own so certificates (or client.cert and
+// client.key for mutual TLS) the user put there themselves stay as they
+// are.
+
+const registryCAFileName = "isolator-ca.crt"
+
+func registryCertsDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".config/containers/certs.d")
+}
+
+// sortedRegistryCAs lists the registries [registries] -> ca_files covers,
+// sorted.
+func sortedRegistryCAs(cfg Config) []string {
+	names := make([]string, 0, len(cfg.RegistryCAFiles))
+	for r := range cfg.RegistryCAFiles {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkRegistryCA makes sure a ca_files entry holds at least one PEM
+// certificate, which is all podman will accept there.
+func checkRegistryCA(registry, path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[registries] ca_files %s: %v", registry, err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("[registries] ca_files %s: %s has no PEM certificates", registry, path)
+	}
+	return data, nil
+}
+
+// SyncRegistryCAs copies each ca_files certificate into its registry's
+// certs.d directory and removes the copies of registries no longer
+// listed. Like SyncRegistriesConf, unchanged files aren't rewritten.
+func SyncRegistryCAs(cfg Config) error {
+	root := registryCertsDir()
+	if entries, err := os.ReadDir(root); err == nil {
+		for _, e := range entries {
+			if _, ok := cfg.RegistryCAFiles[e.Name()]; !ok {
+				_ = os.Remove(filepath.Join(root, e.Name(), registryCAFileName))
+			}
+		}
+	}
+	var errs []string
+	for _, r := range sortedRegistryCAs(cfg) {
+		want, err := checkRegistryCA(r, cfg.RegistryCAFiles[r])
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		path := filepath.Join(root, r, registryCAFileName)
+		if have, err := os.ReadFile(path); err == nil && bytes.Equal(have, want) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, want, 0644); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// imageRegistry returns the registry an image reference names, or "" for
+// a short name that podman will resolve through the search list. Like
+// podman, a first component counts as a registry only if it looks like a
+// host (has a dot or a port, or is localhost).
+func imageRegistry(image string) string {
+	first, _, ok := strings.Cut(image, "/")
+	if !ok {
+		return ""
+	}
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return first
+	}
+	return ""
+}
+
+// checkRegistryPolicy fails early, with a message naming config.hk, for
+// an image from a blocked registry — podman's own error only points at
+// registries.conf, which the user never edited.
+func checkRegistryPolicy(cfg Config, image string) error {
+	reg := imageRegistry(image)
+	if reg == "" {
+		return nil
+	}
+	for _, b := range cfg.RegistryBlocked {
+		if b == reg || strings.HasPrefix(image, strings.TrimSuffix(b, "/")+"/") {
+			return fmt.Errorf("%s comes from %s, which is blocked by [registries] -> blocked in config.hk", image, b)
+		}
+	}
+	return nil
+}
+
+// pulledFrom picks the source that served a pull out of podman's
+// --log-level=debug output, whose lines look like
+//
+//	level=debug msg="Trying to access \"mirror.corp:5000/library/alpine:latest\""
+//
+// It's the last location podman said it was trying, since it stops at the
+// first one that has the image.
+func pulledFrom(output []byte) string {
+	source := ""
+	for _, line := range strings.Split(string(output), "\n") {
+		_, after, ok := strings.Cut(line, "Trying to access ")
+		if !ok {
+			continue
+		}
+		after = strings.TrimLeft(strings.ReplaceAll(after, `\"`, `"`), `"`)
+		if loc, _, ok := strings.Cut(after, `"`); ok {
+			source = loc
+		}
+	}
+	return source
+}
+
+// withoutDebugLines drops podman's level=debug log lines, so a failed
+// pull's output shows the errors rather than pages of tracing.
+func withoutDebugLines(output []byte) []byte {
+	var kept []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.Contains(line, "level=debug") {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n"))
+}
@@ -140,10 +140,11 @@ func HandleRemove(pkg string, force bool, dryRun bool) {
 
 	if ip.Isolated {
 		PrintStep(fmt.Sprintf("Removing isolated container '%s'...", ip.Cont))
-		if !ExecCommand(podmanBin, []string{"rm", "--force", ip.Cont}) {
+		if !ExecCommand(podmanBin, []string{"rm", "--force", "--volumes", ip.Cont}) {
 			PrintError("Failed to remove isolated container")
 			return
 		}
+		ForgetContainerOptions(ip.Cont)
 		isolatedHome := filepath.Join(os.Getenv("HOME"), homesDir, pkg)
 		if err := os.RemoveAll(isolatedHome); err != nil {
 			PrintWarn("Failed to remove isolated home dir: " + err.Error())
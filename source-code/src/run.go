@@ -2,23 +2,155 @@ package src
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
 )
 
-// HandleExec runs an arbitrary command inside the container that owns pkg,
-// interactively. This is more flexible than the fixed wrapper script (which
-// always execs the package binary itself) — e.g. `isolator exec firefox --
-// bash` to get a shell for debugging, or to run a companion CLI tool that
-// shipped in the same container.
-func HandleExec(pkg string, cmdArgs []string) {
+// ExecStdio says how `isolator exec` wires the command's standard streams,
+// mirroring podman's (and Docker's) -i/-t flags: Interactive forwards the
+// host's stdin, TTY allocates a pseudo-terminal inside the container.
+// DetachKeys is the key sequence that detaches from an interactive TTY
+// session, leaving the command running ("" disables detaching).
+type ExecStdio struct {
+	Interactive bool
+	TTY         bool
+	DetachKeys  string
+}
+
+// ExecOptions are the per-command `isolator exec` settings beyond stdio,
+// matching podman exec's flags of the same names. User is a name or uid
+// (optionally :group) inside the container; "" keeps the container's
+// default user. Env entries are NAME=VALUE, or a bare NAME to forward
+// just that variable's host value — nothing else from the host
+// environment is passed. Workdir is an absolute path inside the
+// container. Privileged gives the command podman's extended privileges,
+// for debugging something the container's restrictions get in the way of.
+type ExecOptions struct {
+	User       string
+	Env        []string
+	Workdir    string
+	Privileged bool
+}
+
+var (
+	execUserRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+(:[A-Za-z0-9_.-]+)?$`)
+	envNameRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Validate checks the options before they reach podman's command line.
+func (o ExecOptions) Validate() error {
+	if o.User != "" && !execUserRe.MatchString(o.User) {
+		return fmt.Errorf("--user %q should be a user name or uid, optionally followed by :group", o.User)
+	}
+	for _, kv := range o.Env {
+		if name, _, _ := strings.Cut(kv, "="); !envNameRe.MatchString(name) {
+			return fmt.Errorf("--env %q should be NAME=VALUE or NAME", kv)
+		}
+	}
+	if o.Workdir != "" && (!filepath.IsAbs(o.Workdir) || strings.ContainsRune(o.Workdir, '\n')) {
+		return fmt.Errorf("--workdir %q should be an absolute path inside the container", o.Workdir)
+	}
+	return nil
+}
+
+// execOptionArgs returns the podman exec flags for o.
+func execOptionArgs(o ExecOptions) []string {
+	var args []string
+	if o.User != "" {
+		args = append(args, "--user", o.User)
+	}
+	for _, kv := range o.Env {
+		args = append(args, "--env", kv)
+	}
+	if o.Workdir != "" {
+		args = append(args, "--workdir", o.Workdir)
+	}
+	if o.Privileged {
+		args = append(args, "--privileged")
+	}
+	return args
+}
+
+// DefaultDetachKeys is podman's (and Docker's) own default sequence.
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// ParseDetachKeys turns a detach sequence like "ctrl-x,ctrl-d" into the
+// bytes the terminal actually sends, using the same notation podman does:
+// a comma-separated list where each entry is either a single character or
+// "ctrl-" followed by a letter or one of @ [ \ ] ^ _. An empty spec
+// returns nil, meaning detaching is disabled.
+func ParseDetachKeys(spec string) ([]byte, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var seq []byte
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		if len(key) == 1 {
+			seq = append(seq, key[0])
+			continue
+		}
+		chord, ok := strings.CutPrefix(strings.ToLower(key), "ctrl-")
+		if !ok || len(chord) != 1 {
+			return nil, fmt.Errorf("detach key %q is not valid (expected e.g. 'ctrl-p' or a single character)", key)
+		}
+		switch c := chord[0]; {
+		case c >= 'a' && c <= 'z':
+			seq = append(seq, c-'a'+1)
+		case c == '@':
+			seq = append(seq, 0)
+		case c >= '[' && c <= '_':
+			seq = append(seq, c-'@')
+		default:
+			return nil, fmt.Errorf("detach key %q is not valid (ctrl- must be followed by a letter or one of @ [ \\ ] ^ _)", key)
+		}
+	}
+	return seq, nil
+}
+
+// DefaultExecStdio is what `isolator exec` uses when neither -i nor -t is
+// given: `-it` when it's run from an interactive terminal (so `isolator
+// exec firefox -- bash` keeps dropping you into a usable shell), and
+// neither when stdin or stdout is a pipe/file — so a script running
+// `isolator exec pkg -- cmd` never has its own stdin swallowed by the
+// container command.
+func DefaultExecStdio() ExecStdio {
+	tty := term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+	return ExecStdio{Interactive: tty, TTY: tty, DetachKeys: LoadConfig().DetachKeys}
+}
+
+// HandleExec runs an arbitrary command inside the container that owns pkg.
+// This is more flexible than the fixed wrapper script (which always execs
+// the package binary itself) — e.g. `isolator exec firefox -- bash` to get
+// a shell for debugging, or to run a companion CLI tool that shipped in the
+// same container.
+//
+// It returns the exit code to exit with: the command's own, exactly as
+// podman reports it (126 when the command isn't executable in the
+// container, 127 when it doesn't exist there), or 125 when Isolator or
+// podman failed before the command could run.
+func HandleExec(pkg string, cmdArgs []string, stdio ExecStdio, opts ExecOptions) int {
 	if err := ValidatePackageName(pkg); err != nil {
 		PrintError(err.Error())
-		return
+		return execFailedCode
+	}
+	if _, err := ParseDetachKeys(stdio.DetachKeys); err != nil {
+		PrintError("--detach-keys: " + err.Error())
+		return execFailedCode
+	}
+	if err := opts.Validate(); err != nil {
+		PrintError(err.Error())
+		return execFailedCode
 	}
 
 	installed, err := LoadInstalled()
 	if err != nil {
 		PrintError("Failed to load installed packages")
-		return
+		return execFailedCode
 	}
 	var ip *InstalledPackage
 	for i := range installed {
@@ -29,12 +161,7 @@ func HandleExec(pkg string, cmdArgs []string) {
 	}
 	if ip == nil {
 		PrintError(fmt.Sprintf("Package '%s' is not installed", pkg))
-		return
-	}
-
-	if !EnsureContainerRunning(ip.Cont) {
-		PrintError(fmt.Sprintf("Failed to start container '%s'", ip.Cont))
-		return
+		return execFailedCode
 	}
 
 	command := pkg
@@ -42,10 +169,47 @@ func HandleExec(pkg string, cmdArgs []string) {
 		command = cmdArgs[0]
 		cmdArgs = cmdArgs[1:]
 	}
+	return execInContainer(ip.Cont, command, cmdArgs, stdio, opts)
+}
 
-	args := []string{"exec", "-it", ip.Cont, command}
+// execInContainer starts cont if needed and runs command in it through
+// `podman exec`, returning the exit code as HandleExec describes.
+func execInContainer(cont, command string, cmdArgs []string, stdio ExecStdio, opts ExecOptions) int {
+	if !EnsureContainerRunning(cont) {
+		PrintError(fmt.Sprintf("Failed to start container '%s'", cont))
+		return execFailedCode
+	}
+
+	args := []string{"exec"}
+	if stdio.Interactive {
+		args = append(args, "--interactive")
+	}
+	if stdio.TTY {
+		args = append(args, "--tty")
+	}
+	// Podman does the detach detection itself, in the same loop that
+	// copies stdin to the exec session, so ordinary keystrokes pass
+	// straight through; the flag is only meaningful with a TTY. Podman
+	// doesn't say afterwards whether the session detached or exited, so
+	// the hint is given up front instead.
+	detaching := stdio.Interactive && stdio.TTY
+	if detaching {
+		args = append(args, "--detach-keys="+stdio.DetachKeys)
+		if stdio.DetachKeys != "" {
+			fmt.Fprintln(os.Stderr, DimStyle.Render(fmt.Sprintf("(press %s to detach; %s keeps running in %s)", stdio.DetachKeys, command, cont)))
+		}
+	}
+	args = append(args, execOptionArgs(opts)...)
+	args = append(args, cont, command)
 	args = append(args, cmdArgs...)
-	if !ExecCommand(podmanBin, args) {
-		PrintError("Command failed inside container")
+	code, err := ExecCommandExitCode(podmanBin, args, stdio.Interactive)
+	if err != nil {
+		PrintError("Failed to run podman exec: " + err.Error())
+		return execFailedCode
 	}
+	return code
 }
+
+// execFailedCode is what podman itself exits with when it fails before
+// the command runs, so callers can't mistake it for the command's own.
+const execFailedCode = 125
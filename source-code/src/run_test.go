@@ -0,0 +1,82 @@
+package src
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	cases := map[string][]byte{
+		"":              nil,
+		"ctrl-p,ctrl-q": {0x10, 0x11},
+		"ctrl-x,ctrl-d": {0x18, 0x04},
+		"ctrl-@,ctrl-_": {0x00, 0x1f},
+		"ctrl-[,q":      {0x1b, 'q'},
+	}
+	for spec, want := range cases {
+		got, err := ParseDetachKeys(spec)
+		if err != nil {
+			t.Errorf("ParseDetachKeys(%q) failed: %v", spec, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ParseDetachKeys(%q) = %v, want %v", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{"ctrl-", "ctrl-pq", "alt-x", "ctrl-1", "ctrl-p,,ctrl-q"} {
+		if _, err := ParseDetachKeys(spec); err == nil {
+			t.Errorf("expected ParseDetachKeys(%q) to fail", spec)
+		}
+	}
+}
+
+func TestExecOptions(t *testing.T) {
+	valid := []ExecOptions{
+		{},
+		{User: "root"},
+		{User: "1000:1000"},
+		{Env: []string{"DEBUG=1", "EMPTY=", "TERM"}},
+		{Workdir: "/"},
+		{Workdir: "/home/user/project"},
+	}
+	for _, o := range valid {
+		if err := o.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got error: %v", o, err)
+		}
+	}
+	invalid := []ExecOptions{
+		{User: "root; id"},
+		{User: "a:b:c"},
+		{Env: []string{"=value"}},
+		{Env: []string{"1ABC=x"}},
+		{Workdir: "relative/dir"},
+	}
+	for _, o := range invalid {
+		if err := o.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected, but it passed validation", o)
+		}
+	}
+
+	got := execOptionArgs(ExecOptions{User: "0", Env: []string{"A=1", "TERM"}, Workdir: "/tmp", Privileged: true})
+	want := []string{"--user", "0", "--env", "A=1", "--env", "TERM", "--workdir", "/tmp", "--privileged"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("execOptionArgs = %q, want %q", got, want)
+	}
+}
+
+func TestShellScriptParses(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh on this host")
+	}
+	script := shellScript("debian-testing")
+	if !strings.Contains(script, "(isolator:debian-testing)") {
+		t.Errorf("prompt doesn't name the container:\n%s", script)
+	}
+	if out, err := exec.Command(sh, "-n", "-c", script).CombinedOutput(); err != nil {
+		t.Errorf("shell script doesn't parse: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,1022 @@
+package src
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunOptions holds the per-container `podman run` settings a user can pick
+// at install time (flags on `isolator install`, with config.hk supplying
+// the defaults), on top of everything getPodmanRunArgs already derives from
+// the package type and the host.
+//
+// A shared distro container is only created once — by whichever install
+// needs it first — so these settings only ever take effect when a *new*
+// container gets created. They're recorded per container in containers.hk
+// so `rollback` recreates the container exactly as it was, and so a later
+// install that asks for different options can say why it has no effect
+// instead of silently ignoring them.
+type RunOptions struct {
+	// Timezone is "" (the image's own default — UTC for every supported
+	// distro, which keeps containers reproducible), "local" (follow the
+	// host), or an IANA zone name like "Europe/Warsaw".
+	Timezone string
+	// Locale is "" (the image's default, usually "C"/POSIX) or "host",
+	// which passes the host's LANG/LANGUAGE/LC_* variables through.
+	Locale string
+	// Runtime is the OCI runtime podman should hand the container to
+	// ("crun", "runc", "youki", or a path to one); "" keeps podman's own
+	// configured default. Podman generates the OCI bundle (rootfs +
+	// config.json) itself and remembers the runtime per container, so every
+	// later `podman exec`/`start` keeps using the same one.
+	Runtime string
+	// NoPasswdEntry skips synthesizing /etc/passwd and /etc/group entries
+	// for the mapped host user — for images that resolve users through
+	// NSS modules of their own and shouldn't have those files touched.
+	NoPasswdEntry bool
+	// PasswdFile and GroupFile are host files bind-mounted read-only over
+	// the container's /etc/passwd and /etc/group — a centrally managed
+	// user database, or one for a rootfs that ships none. They're checked
+	// to be in passwd(5)/group(5) format before the container is created.
+	PasswdFile string
+	GroupFile  string
+	// NoContainerEnv blanks out /run/.containerenv — the key=value
+	// metadata file (engine, name, id, image) podman creates inside every
+	// container — for setups that don't want the container learning where
+	// it came from. The ISOLATOR_* variables are still set.
+	NoContainerEnv bool
+	// NoHosts and NoResolvConf stop podman from generating /etc/hosts and
+	// /etc/resolv.conf, leaving the image's own files, for workloads that
+	// manage name resolution themselves (a DNS-over-HTTPS resolver, say).
+	NoHosts      bool
+	NoResolvConf bool
+	// NoImageVolumes ignores the image's VOLUME directives. By default
+	// podman gives each declared path an anonymous volume, so a database's
+	// data directory gets storage of its own outside the container's
+	// overlay; with this set those paths are plain directories in it.
+	NoImageVolumes bool
+	// MachineID is "" (an ID generated for this container), "host" (the
+	// host's /etc/machine-id) or "none" (the image's own) — see
+	// machineid.go.
+	MachineID string
+	// SeccompProfile is a host path to a seccomp profile in Docker's JSON
+	// format, used instead of podman's default filter, or "unconfined"
+	// for none — see seccomp.go.
+	SeccompProfile string
+	// GroupAdd "keep-groups" keeps the user's host supplementary groups on
+	// the container's processes — see buildGroupAddArgs.
+	GroupAdd string
+	// RootFS is a host directory to create the container from instead of
+	// the distro's image, with :O to overlay it rather than write into
+	// it — see rootfs.go. Per-install only, like IP.
+	RootFS string
+	// CapDropAll starts the container with no capabilities at all instead
+	// of podman's default set; CapAdd then lists the only ones given back
+	// (or, without CapDropAll, extra ones on top of the defaults). Names
+	// are accepted with or without the CAP_ prefix, in any case.
+	CapDropAll bool
+	CapAdd     []string
+	// DeviceInput shares the host's input devices (gamepads, joysticks,
+	// evdev nodes, /dev/uinput) plus the udev metadata needed to identify
+	// them — see buildInputDeviceArgs.
+	DeviceInput bool
+	// DeviceKVM shares /dev/kvm (plus /dev/vhost-net and /dev/net/tun)
+	// for running hardware-accelerated VMs inside the container.
+	DeviceKVM bool
+	// DeviceVideo shares every /dev/video* and /dev/media* node (webcams,
+	// capture cards) for video calls and computer-vision work.
+	DeviceVideo bool
+	// PrivilegedDevices shares every GPU, video and sound node
+	// (/dev/dri/*, /dev/nvidia*, /dev/video*, /dev/snd/*) without the rest
+	// of what --privileged grants — see buildPrivilegedDeviceArgs.
+	PrivilegedDevices bool
+	// MaskPaths are extra container paths hidden from the workload (podman
+	// mounts /dev/null over files and an empty read-only tmpfs over
+	// directories). NoMaskPaths drops podman's default masks — /proc/kcore,
+	// /proc/keys, /proc/acpi, /sys/firmware and the rest of Docker's list —
+	// for tools that really need to read them; MaskPaths still apply.
+	MaskPaths   []string
+	NoMaskPaths bool
+	// SysFS is "" or "read-only" (podman's default: /sys read-only, with
+	// /sys/firmware, /sys/fs/selinux, /sys/dev/block and
+	// /sys/devices/virtual/powercap masked), "masked" (those plus the
+	// securityfs and debugfs mount points) or "unconfined" (no /sys masks,
+	// still read-only) — see buildSysfsArgs.
+	SysFS string
+	// DeviceFUSE shares /dev/fuse and allows FUSE mounts inside the
+	// container (AppImages, sshfs, rclone mount, fuse-overlayfs).
+	DeviceFUSE bool
+	// USB lists host USB devices to pass through, each as vendor:product
+	// or bus.device. It's a per-install choice, not a config default.
+	USB []string
+	// DeviceCgroupRules are devices.allow-style rules ("c 189:* rwm");
+	// every host device a rule matches is passed through as well, so the
+	// permission comes with a node to open — see buildCgroupRuleArgs.
+	DeviceCgroupRules []string
+	// CgroupNS is "" (podman's default: private on cgroup v2 hosts, host
+	// on v1), "private", "host" or "auto" (private wherever the kernel has
+	// cgroup namespaces). In a private cgroup namespace the container's own
+	// subtree is the root of a cgroup2 mount at /sys/fs/cgroup, so it can't
+	// see the host's hierarchy. On kernels without cgroup namespaces
+	// (before 4.6) "private" falls back to host — see effectiveCgroupNS.
+	CgroupNS string
+	// CgroupRW makes the container's own cgroup2 mount writable, for
+	// running systemd or another cgroup manager inside it; podman mounts
+	// it read-only otherwise.
+	CgroupRW bool
+	// StorageSize caps the container's writable layer ("20G"), so one
+	// container can't fill the disk. It needs overlay on XFS; elsewhere
+	// it's skipped with a warning — see buildStorageArgs.
+	StorageSize string
+	// Mounts are extra --mount specs (type=bind or type=volume, Docker's
+	// key=value syntax) — see parseMountSpec.
+	Mounts []string
+	// PID is "" (a PID namespace of the container's own) or
+	// "container:NAME", joining another container's so its processes can
+	// be straced or gdb'd from this one without --privileged. It's a
+	// per-install choice, not a config default.
+	PID string
+	// Publish lists host ports forwarded into the container — ranges, UDP
+	// and a host address are all allowed, see parsePortSpec. Per-install
+	// only, like USB: a default would clash as soon as two containers
+	// were created with it.
+	Publish []string
+	// DeviceReadIOPS and DeviceWriteIOPS cap read/write operations per
+	// second on host block devices, as "/dev/nvme0n1:1000" — so a
+	// container can't swamp a shared SSD with small random I/O.
+	DeviceReadIOPS  []string
+	DeviceWriteIOPS []string
+	// CPUShares (Docker's 2-262144 scale, default 1024) or CPUWeight
+	// (cgroup v2's 1-10000, default 100) sets the container's share of CPU
+	// time under contention; 0 leaves the default. Only one may be set —
+	// see cpu.go for how each maps onto v1 and v2 hosts.
+	CPUShares int
+	CPUWeight int
+	// MemoryNodes pins the container's memory to these NUMA nodes
+	// (cpuset.mems, kernel list syntax: "0", "0-1", "0,2"), so latency-
+	// sensitive workloads don't end up reading memory across the
+	// interconnect after a CPU migration. "" allows every node.
+	MemoryNodes string
+	// Umask is the file creation mask, in octal ("0027"), for the
+	// container's processes — podman applies it to every later exec too —
+	// so files and sockets they create aren't world-readable. "" keeps
+	// podman's default, 0022.
+	Umask string
+	// Labels are key=value container labels, each key prefixed with
+	// LabelNamespace (a reverse-DNS "com.example") when one is set, so
+	// labels from different tools and teams don't collide.
+	Labels         []string
+	LabelNamespace string
+	// Annotations are key=value OCI annotations, stored as given (no
+	// namespace prefix) since shims and tools look them up by their exact
+	// keys, io.kubernetes.pod.name say.
+	Annotations []string
+	// Ulimits are --ulimit TYPE=SOFT[:HARD] entries. core and nofile get
+	// hardening defaults unless listed here — see ulimits.go.
+	Ulimits []string
+	// CgroupConf are raw cgroup v2 file writes, "cpu.idle=1", for knobs
+	// there's no dedicated option for — see cgroupconf.go.
+	CgroupConf []string
+	// Sysctls are KEY=VALUE kernel parameters of the container's own
+	// network and IPC namespaces, net.core.somaxconn say — see sysctl.go.
+	Sysctls []string
+	// ProcOpts are options for the container's /proc mount, e.g.
+	// "hidepid=2" so processes of a multi-user or shared-PID container
+	// can't read each other's command lines — see proc.go.
+	ProcOpts string
+	// Network is "" (podman's default NAT networking) or
+	// "macvlan:IFACE[:MODE]" / "ipvlan:IFACE[:MODE]", putting the
+	// container directly on the host NIC's LAN; IP is its static IPv4
+	// address there, "" for DHCP. IP is per-install only, like Publish.
+	// MACAddress is a macvlan interface's MAC: "" or "auto" for one
+	// derived from the container's name, "random", or an address — see
+	// network.go. It's per-install only too: one fixed address can't be
+	// every container's default.
+	Network    string
+	IP         string
+	MACAddress string
+	// OOMKillDisable exempts the container from the kernel's OOM killer,
+	// for databases that must never be killed mid-write — at the risk of
+	// the host hanging instead. It needs a second, explicit acknowledgement
+	// on the command line, so it's never a config or profile default.
+	OOMKillDisable bool
+	// Unrecorded marks the options of a container that has no
+	// containers.hk entry, so was created before run options were
+	// recorded. Recreating it (on rollback) leaves out the defaults
+	// added since — the synthesized passwd and group entries and the
+	// core/nofile ulimits — so it comes back as it was made. It's never
+	// stored.
+	Unrecorded bool
+}
+
+// RunOptionsFromConfig returns the defaults config.hk asks for, which
+// install flags then override individually.
+func RunOptionsFromConfig(cfg Config) RunOptions {
+	return RunOptions{
+		Timezone:          cfg.Timezone,
+		Locale:            cfg.Locale,
+		Runtime:           cfg.Runtime,
+		NoPasswdEntry:     !cfg.PasswdEntry,
+		PasswdFile:        cfg.PasswdFile,
+		GroupFile:         cfg.GroupFile,
+		NoContainerEnv:    !cfg.ContainerEnv,
+		NoHosts:           !cfg.Hosts,
+		NoResolvConf:      !cfg.ResolvConf,
+		NoImageVolumes:    !cfg.ImageVolumes,
+		MachineID:         cfg.MachineID,
+		SeccompProfile:    cfg.SeccompProfile,
+		GroupAdd:          cfg.GroupAdd,
+		CapDropAll:        cfg.CapDropAll,
+		CapAdd:            cfg.CapAdd,
+		DeviceInput:       cfg.DeviceInput,
+		DeviceKVM:         cfg.DeviceKVM,
+		DeviceVideo:       cfg.DeviceVideo,
+		PrivilegedDevices: cfg.PrivilegedDevices,
+		MaskPaths:         cfg.MaskPaths,
+		NoMaskPaths:       !cfg.DefaultMaskPaths,
+		SysFS:             cfg.SysFS,
+		DeviceFUSE:        cfg.DeviceFUSE,
+		DeviceCgroupRules: cfg.DeviceCgroupRules,
+		CgroupNS:          cfg.CgroupNS,
+		CgroupRW:          cfg.CgroupRW,
+		StorageSize:       cfg.StorageSize,
+		Mounts:            cfg.Mounts,
+		DeviceReadIOPS:    cfg.DeviceReadIOPS,
+		DeviceWriteIOPS:   cfg.DeviceWriteIOPS,
+		CPUShares:         cfg.CPUShares,
+		CPUWeight:         cfg.CPUWeight,
+		MemoryNodes:       cfg.MemoryNodes,
+		Umask:             cfg.Umask,
+		Labels:            cfg.Labels,
+		Annotations:       cfg.Annotations,
+		LabelNamespace:    cfg.LabelNamespace,
+		Ulimits:           cfg.Ulimits,
+		CgroupConf:        cfg.CgroupConf,
+		Sysctls:           cfg.Sysctls,
+		ProcOpts:          cfg.ProcOpts,
+		Network:           cfg.Network,
+	}
+}
+
+var (
+	timezoneNameRe = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+	runtimeNameRe  = regexp.MustCompile(`^[A-Za-z0-9._/+-]+$`)
+	// containerNameRe is podman's own rule for container names.
+	containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Validate checks every option before any of them ends up on a podman
+// command line.
+func (o RunOptions) Validate() error {
+	switch o.Timezone {
+	case "", "local":
+	default:
+		if !timezoneNameRe.MatchString(o.Timezone) {
+			return fmt.Errorf("--tz %q is not a valid timezone name (expected 'local' or e.g. 'Europe/Warsaw')", o.Timezone)
+		}
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("--tz %q: unknown timezone (no such zoneinfo file on this host)", o.Timezone)
+		}
+	}
+	switch o.Locale {
+	case "", "host":
+	default:
+		return fmt.Errorf("--locale %q is not supported (expected 'host', or leave unset for the image default)", o.Locale)
+	}
+	if o.Runtime != "" {
+		if !runtimeNameRe.MatchString(o.Runtime) {
+			return fmt.Errorf("--runtime %q is not a valid runtime name or path", o.Runtime)
+		}
+		if _, err := exec.LookPath(o.Runtime); err != nil {
+			return fmt.Errorf("--runtime %q: not found on PATH (install it, e.g. the distro's crun/runc package, or pass a full path)", o.Runtime)
+		}
+	}
+	if err := validateMachineID(o.MachineID); err != nil {
+		return err
+	}
+	if o.SeccompProfile != "" {
+		if err := validateSeccompProfile(o.SeccompProfile); err != nil {
+			return err
+		}
+	}
+	if o.GroupAdd != "" {
+		if err := validateGroupAdd(o.GroupAdd); err != nil {
+			return err
+		}
+	}
+	if o.RootFS != "" {
+		if err := validateRootFS(o.RootFS); err != nil {
+			return err
+		}
+	}
+	switch o.CgroupNS {
+	case "", "private", "host", "auto":
+	default:
+		return fmt.Errorf("--cgroupns %q is not supported (expected 'private', 'host' or 'auto')", o.CgroupNS)
+	}
+	if o.CgroupRW && o.CgroupNS == "host" {
+		return fmt.Errorf("--cgroup-rw with --cgroupns host would make the host's whole cgroup tree writable; use a private cgroup namespace")
+	}
+	if o.PID != "" {
+		if target, ok := strings.CutPrefix(o.PID, "container:"); !ok || !containerNameRe.MatchString(target) {
+			return fmt.Errorf("--pid %q is not supported (expected container:NAME)", o.PID)
+		}
+	}
+	if o.PasswdFile != "" {
+		if err := validateUserDBFile(o.PasswdFile, 7); err != nil {
+			return fmt.Errorf("--passwd-file: %v", err)
+		}
+	}
+	if o.GroupFile != "" {
+		if err := validateUserDBFile(o.GroupFile, 4); err != nil {
+			return fmt.Errorf("--group-file: %v", err)
+		}
+	}
+	if o.StorageSize != "" {
+		if err := validateStorageSize(o.StorageSize); err != nil {
+			return fmt.Errorf("--storage-size: %v", err)
+		}
+	}
+	for _, c := range o.CapAdd {
+		if _, err := NormalizeCapability(c); err != nil {
+			return fmt.Errorf("--cap-add: %v", err)
+		}
+	}
+	for _, spec := range o.USB {
+		if err := validateUSBSpec(spec); err != nil {
+			return fmt.Errorf("--usb: %v", err)
+		}
+	}
+	for _, rule := range o.DeviceCgroupRules {
+		if err := validateCgroupRule(rule); err != nil {
+			return fmt.Errorf("--device-cgroup-rule: %v", err)
+		}
+	}
+	for _, spec := range o.DeviceReadIOPS {
+		if _, _, err := parseIOPSLimit(spec); err != nil {
+			return fmt.Errorf("--device-read-iops: %v", err)
+		}
+	}
+	for _, spec := range o.DeviceWriteIOPS {
+		if _, _, err := parseIOPSLimit(spec); err != nil {
+			return fmt.Errorf("--device-write-iops: %v", err)
+		}
+	}
+	if err := validateCPUShare(o.CPUShares, o.CPUWeight); err != nil {
+		return err
+	}
+	if o.MemoryNodes != "" {
+		if _, err := parseNodeList(o.MemoryNodes); err != nil {
+			return fmt.Errorf("--memory-node-pin: %v", err)
+		}
+	}
+	if o.Umask != "" {
+		if m, err := strconv.ParseUint(o.Umask, 8, 32); err != nil || m > 0777 {
+			return fmt.Errorf("--umask %q is not an octal mask like 0027", o.Umask)
+		}
+	}
+	if o.LabelNamespace != "" {
+		if err := validateLabelNamespace(o.LabelNamespace); err != nil {
+			return err
+		}
+	}
+	for _, l := range o.Labels {
+		if err := validateLabel(l); err != nil {
+			return err
+		}
+	}
+	for _, a := range o.Annotations {
+		if err := validateAnnotation(a); err != nil {
+			return err
+		}
+	}
+	for _, u := range o.Ulimits {
+		if err := validateUlimit(u); err != nil {
+			return err
+		}
+	}
+	if err := validateCgroupConf(o.CgroupConf, o.CPUShares, o.CPUWeight); err != nil {
+		return err
+	}
+	if err := validateSysctls(o.Sysctls); err != nil {
+		return err
+	}
+	if o.ProcOpts != "" {
+		if err := validateProcOpts(o.ProcOpts); err != nil {
+			return err
+		}
+	}
+	if err := validateNetwork(o.Network, o.IP, o.Publish); err != nil {
+		return err
+	}
+	if err := validateMACAddress(o.MACAddress, o.Network); err != nil {
+		return err
+	}
+	for _, spec := range o.Publish {
+		if _, err := parsePortSpec(spec); err != nil {
+			return fmt.Errorf("--publish %q: %v", spec, err)
+		}
+	}
+	for _, spec := range o.Mounts {
+		if _, err := parseMountSpec(spec); err != nil {
+			return fmt.Errorf("--mount %q: %v", spec, err)
+		}
+	}
+	for _, p := range o.MaskPaths {
+		if err := validateContainerPath(p); err != nil {
+			return fmt.Errorf("--mask-path: %v", err)
+		}
+	}
+	switch o.SysFS {
+	case "", "read-only", "masked", "unconfined":
+	case "rw":
+		return fmt.Errorf("--sysfs rw isn't available: podman only mounts /sys writable for --privileged containers ('unconfined' drops the /sys masks, but /sys stays read-only)")
+	default:
+		return fmt.Errorf("--sysfs %q is not supported (expected 'read-only', 'masked' or 'unconfined')", o.SysFS)
+	}
+	return nil
+}
+
+// Summary renders the non-default options as short "key=value" strings,
+// for dry-run output and mismatch warnings.
+func (o RunOptions) Summary() []string {
+	var s []string
+	if o.Timezone != "" {
+		s = append(s, "tz="+o.Timezone)
+	}
+	if o.Locale != "" {
+		s = append(s, "locale="+o.Locale)
+	}
+	if o.Runtime != "" {
+		s = append(s, "runtime="+o.Runtime)
+	}
+	if o.NoPasswdEntry {
+		s = append(s, "no-passwd-entry")
+	}
+	if o.PasswdFile != "" {
+		s = append(s, "passwd-file="+o.PasswdFile)
+	}
+	if o.GroupFile != "" {
+		s = append(s, "group-file="+o.GroupFile)
+	}
+	if o.NoContainerEnv {
+		s = append(s, "no-containerenv")
+	}
+	if o.NoHosts {
+		s = append(s, "no-hosts")
+	}
+	if o.NoResolvConf {
+		s = append(s, "no-resolv-conf")
+	}
+	if o.NoImageVolumes {
+		s = append(s, "no-image-volumes")
+	}
+	if o.MachineID != "" {
+		s = append(s, "machine-id="+o.MachineID)
+	}
+	if o.SeccompProfile != "" {
+		s = append(s, "seccomp-profile="+o.SeccompProfile)
+	}
+	if o.GroupAdd != "" {
+		s = append(s, "group-add="+o.GroupAdd)
+	}
+	if o.RootFS != "" {
+		s = append(s, "rootfs="+o.RootFS)
+	}
+	if o.CapDropAll {
+		s = append(s, "cap-drop-all")
+	}
+	if len(o.CapAdd) > 0 {
+		s = append(s, "cap-add="+strings.Join(o.CapAdd, ","))
+	}
+	if o.DeviceInput {
+		s = append(s, "device-input")
+	}
+	if o.DeviceKVM {
+		s = append(s, "device-kvm")
+	}
+	if o.DeviceVideo {
+		s = append(s, "device-video")
+	}
+	if o.PrivilegedDevices {
+		s = append(s, "privileged-devices")
+	}
+	if len(o.MaskPaths) > 0 {
+		s = append(s, "mask-path="+strings.Join(o.MaskPaths, ","))
+	}
+	if o.NoMaskPaths {
+		s = append(s, "no-mask-paths")
+	}
+	if o.SysFS != "" && o.SysFS != "read-only" {
+		s = append(s, "sysfs="+o.SysFS)
+	}
+	if o.DeviceFUSE {
+		s = append(s, "device-fuse")
+	}
+	if len(o.USB) > 0 {
+		s = append(s, "usb="+strings.Join(o.USB, ","))
+	}
+	for _, rule := range o.DeviceCgroupRules {
+		s = append(s, "device-cgroup-rule="+rule)
+	}
+	if o.CgroupNS != "" {
+		s = append(s, "cgroupns="+o.CgroupNS)
+	}
+	if o.CgroupRW {
+		s = append(s, "cgroup-rw")
+	}
+	if o.StorageSize != "" {
+		s = append(s, "storage-size="+o.StorageSize)
+	}
+	for _, spec := range o.Mounts {
+		s = append(s, "mount="+spec)
+	}
+	if o.PID != "" {
+		s = append(s, "pid="+o.PID)
+	}
+	if len(o.Publish) > 0 {
+		s = append(s, "publish="+strings.Join(o.Publish, ","))
+	}
+	if len(o.DeviceReadIOPS) > 0 {
+		s = append(s, "device-read-iops="+strings.Join(o.DeviceReadIOPS, ","))
+	}
+	if len(o.DeviceWriteIOPS) > 0 {
+		s = append(s, "device-write-iops="+strings.Join(o.DeviceWriteIOPS, ","))
+	}
+	if o.CPUShares != 0 {
+		s = append(s, fmt.Sprintf("cpu-shares=%d", o.CPUShares))
+	}
+	if o.CPUWeight != 0 {
+		s = append(s, fmt.Sprintf("cpu-weight=%d", o.CPUWeight))
+	}
+	if o.MemoryNodes != "" {
+		s = append(s, "memory-node-pin="+o.MemoryNodes)
+	}
+	if o.Umask != "" {
+		s = append(s, "umask="+o.Umask)
+	}
+	for _, l := range o.Labels {
+		s = append(s, "label="+namespacedLabel(o.LabelNamespace, l))
+	}
+	for _, a := range o.Annotations {
+		s = append(s, "annotation="+a)
+	}
+	for _, u := range o.Ulimits {
+		s = append(s, "ulimit="+u)
+	}
+	for _, c := range o.CgroupConf {
+		s = append(s, "cgroup-conf="+c)
+	}
+	for _, c := range o.Sysctls {
+		s = append(s, "sysctl="+c)
+	}
+	if o.ProcOpts != "" {
+		s = append(s, "proc-opts="+o.ProcOpts)
+	}
+	if o.Network != "" {
+		s = append(s, "network="+o.Network)
+	}
+	if o.IP != "" {
+		s = append(s, "ip="+o.IP)
+	}
+	if o.MACAddress != "" {
+		s = append(s, "mac-address="+o.MACAddress)
+	}
+	if o.OOMKillDisable {
+		s = append(s, "oom-kill-disable")
+	}
+	return s
+}
+
+// PIDTarget returns the container whose PID namespace opts joins, or "".
+func (o RunOptions) PIDTarget() string {
+	target, _ := strings.CutPrefix(o.PID, "container:")
+	return target
+}
+
+// Equal reports whether two option sets would produce the same container.
+func (o RunOptions) Equal(other RunOptions) bool {
+	return reflect.DeepEqual(o, other)
+}
+
+// BuildRunOptionArgs returns the extra `podman run` arguments for opts.
+func BuildRunOptionArgs(opts RunOptions) []string {
+	var args []string
+	if !opts.Unrecorded {
+		args = append(args, buildPasswdArgs(opts.NoPasswdEntry, opts.PasswdFile != "", opts.GroupFile != "")...)
+	}
+	args = append(args, buildUserDBArgs(opts.PasswdFile, opts.GroupFile)...)
+	args = append(args, buildTimezoneArgs(opts.Timezone)...)
+	if opts.Locale == "host" {
+		args = append(args, hostLocaleArgs()...)
+	}
+	if opts.NoContainerEnv {
+		args = append(args, "--volume", "/dev/null:/run/.containerenv:ro")
+	}
+	if opts.NoHosts {
+		args = append(args, "--no-hosts")
+	}
+	args = append(args, buildSeccompArgs(opts.SeccompProfile)...)
+	args = append(args, buildGroupAddArgs(opts.GroupAdd)...)
+	if opts.NoResolvConf {
+		// Podman's spelling for "don't create /etc/resolv.conf".
+		args = append(args, "--dns=none")
+	}
+	if opts.NoImageVolumes {
+		args = append(args, "--image-volume", "ignore")
+	}
+	if ns := effectiveCgroupNS(opts.CgroupNS); ns != "" {
+		args = append(args, "--cgroupns="+ns)
+	}
+	if opts.CgroupRW {
+		// /sys/fs/cgroup is one of podman's read-only paths; unmasking it
+		// mounts it read-write instead.
+		args = append(args, "--security-opt", "unmask=/sys/fs/cgroup")
+	}
+	if opts.PID != "" {
+		args = append(args, "--pid="+opts.PID)
+	}
+	args = append(args, buildCapabilityArgs(opts.CapDropAll, opts.CapAdd)...)
+	args = append(args, buildDeviceArgs(opts)...)
+	args = append(args, buildMaskArgs(opts.MaskPaths, opts.NoMaskPaths)...)
+	args = append(args, buildSysfsArgs(opts.SysFS)...)
+	args = append(args, buildStorageArgs(opts.StorageSize)...)
+	args = append(args, buildMountArgs(opts.Mounts)...)
+	args = append(args, buildPublishArgs(opts.Publish)...)
+	args = append(args, buildIOPSArgs(opts.DeviceReadIOPS, opts.DeviceWriteIOPS)...)
+	args = append(args, buildCPUArgs(opts.CPUShares, opts.CPUWeight)...)
+	if opts.MemoryNodes != "" {
+		args = append(args, "--cpuset-mems="+opts.MemoryNodes)
+	}
+	if opts.Umask != "" {
+		args = append(args, "--umask="+opts.Umask)
+	}
+	args = append(args, buildLabelArgs(opts.LabelNamespace, opts.Labels)...)
+	args = append(args, buildAnnotationArgs(opts.Annotations)...)
+	if !opts.Unrecorded {
+		args = append(args, buildUlimitArgs(opts.Ulimits)...)
+	}
+	args = append(args, buildCgroupConfArgs(opts.CgroupConf)...)
+	args = append(args, buildSysctlArgs(opts.Sysctls)...)
+	args = append(args, buildPingArgs(opts.CapAdd, opts.Sysctls)...)
+	args = append(args, buildProcArgs(opts.ProcOpts)...)
+	args = append(args, buildNetworkArgs(opts.Network, opts.IP)...)
+	args = append(args, buildOOMArgs(opts.OOMKillDisable)...)
+	return args
+}
+
+// validateContainerPath accepts clean absolute paths inside the container.
+// ':' is podman's separator inside --security-opt mask=, so it can't
+// appear in one path.
+func validateContainerPath(p string) error {
+	if !filepath.IsAbs(p) || filepath.Clean(p) != p || p == "/" {
+		return fmt.Errorf("%q should be a clean absolute path inside the container, e.g. /proc/kcore", p)
+	}
+	if strings.ContainsAny(p, ":,\n") {
+		return fmt.Errorf("%q contains ':', ',' or a newline, which podman can't pass through", p)
+	}
+	return nil
+}
+
+// buildMaskArgs uses podman's own masking, which is applied after every
+// other mount (so it covers paths exposed by /proc, /sys and bind mounts
+// alike) and knows to use /dev/null for files and an empty tmpfs for
+// directories.
+func buildMaskArgs(paths []string, noDefaults bool) []string {
+	var args []string
+	if noDefaults {
+		args = append(args, "--security-opt", "unmask=ALL")
+	}
+	if len(paths) > 0 {
+		args = append(args, "--security-opt", "mask="+strings.Join(paths, ":"))
+	}
+	return args
+}
+
+// sysfsExtraMasks are what --sysfs masked hides on top of podman's own
+// /sys masks: the mount points of securityfs (LSM policy) and debugfs
+// (kernel internals), in case a runtime or a host-network container
+// exposes the host's mounts there.
+var sysfsExtraMasks = []string{"/sys/kernel/security", "/sys/kernel/debug"}
+
+// buildSysfsArgs adjusts what the container sees of /sys. Podman always
+// mounts it read-only for unprivileged containers, and rootless ones
+// couldn't write to the host's sysfs anyway, so there's no writable
+// ("rw") mode short of --privileged; "unconfined" only lifts the masks.
+func buildSysfsArgs(mode string) []string {
+	switch mode {
+	case "masked":
+		return []string{"--security-opt", "mask=" + strings.Join(sysfsExtraMasks, ":")}
+	case "unconfined":
+		return []string{"--security-opt", "unmask=/sys/*"}
+	}
+	return nil
+}
+
+// buildRuntimeArgs returns podman's global --runtime flag for runtime, or
+// nothing to leave podman's default in charge.
+func buildRuntimeArgs(runtime string) []string {
+	if runtime == "" {
+		return nil
+	}
+	return []string{"--runtime", runtime}
+}
+
+// buildPasswdArgs makes sure the host user mapped in by --userns=keep-id
+// also exists by name inside the container. Minimal images (wolfi,
+// distroless, busybox-based ones) often have no passwd entry for an
+// arbitrary uid, and anything calling getpwuid() — ssh, git, lots of
+// Python — then fails with "no such user". Podman only adds these entries
+// when the uid/gid isn't already listed, so images that do ship a
+// matching user keep theirs; the entry's home is /home/user, the
+// writable bind-mounted home every container gets. A database supplied
+// with --passwd-file/--group-file is left exactly as given.
+func buildPasswdArgs(noEntry, ownPasswd, ownGroup bool) []string {
+	if noEntry || (ownPasswd && ownGroup) {
+		return []string{"--passwd=false"}
+	}
+	var args []string
+	if !ownPasswd {
+		args = append(args, "--passwd-entry", "$USERNAME:*:$UID:$GID:$NAME:/home/user:/bin/sh")
+	}
+	if !ownGroup {
+		args = append(args, "--group-entry", "$GROUPNAME:*:$GID:$USERNAME")
+	}
+	return args
+}
+
+// buildTimezoneArgs relies on podman's own --tz handling, which copies the
+// matching zoneinfo file into the container's /etc/localtime — so it
+// works even on minimal images that ship no tzdata at all — and adds a
+// matching TZ variable for programs that only look at the environment.
+func buildTimezoneArgs(tz string) []string {
+	switch tz {
+	case "":
+		return nil
+	case "local":
+		args := []string{"--tz", "local"}
+		if name := hostTimezoneName(); name != "" {
+			args = append(args, "--env", "TZ="+name)
+		}
+		return args
+	default:
+		return []string{"--tz", tz, "--env", "TZ=" + tz}
+	}
+}
+
+// hostTimezoneName returns the host's zone as an IANA name, from $TZ or by
+// resolving the /etc/localtime symlink into the zoneinfo tree. It returns
+// "" if neither gives a usable answer (podman's --tz=local still works in
+// that case; only the TZ variable is skipped).
+func hostTimezoneName() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && timezoneNameRe.MatchString(tz) {
+		return tz
+	}
+	target, err := filepath.EvalSymlinks("/etc/localtime")
+	if err != nil {
+		return ""
+	}
+	if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+		return target[i+len("zoneinfo/"):]
+	}
+	return ""
+}
+
+// hostLocaleArgs passes LANG, LANGUAGE and every LC_* variable set on the
+// host through to the container.
+func hostLocaleArgs() []string {
+	var args []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == "LANG" || name == "LANGUAGE" || strings.HasPrefix(name, "LC_") {
+			args = append(args, "--env", kv)
+		}
+	}
+	return args
+}
+
+// ---------------------------------------------------------------------------
+// containers.hk — which RunOptions each managed container was created with
+// ---------------------------------------------------------------------------
+
+// Stored as:
+//
+//	[containers]
+//	-> debian-testing
+//	--> timezone => local
+//	--> locale   => host
+//	--> runtime  => crun
+//	--> no_passwd_entry => false
+//	--> passwd_file => /srv/userdb/passwd
+//	--> group_file => /srv/userdb/group
+//	--> no_containerenv => false
+//	--> no_hosts => false
+//	--> no_resolv_conf => false
+//	--> no_image_volumes => false
+//	--> machine_id => host
+//	--> seccomp_profile => /etc/isolator/seccomp-strict.json
+//	--> group_add => keep-groups
+//	--> rootfs => /srv/trees/bookworm:O
+//	--> cap_drop_all => true
+//	--> cap_add => [NET_BIND_SERVICE]
+//	--> device_input => false
+//	--> device_kvm => false
+//	--> device_video => false
+//	--> privileged_devices => false
+//	--> mask_paths => [/proc/cpuinfo]
+//	--> no_mask_paths => false
+//	--> sysfs => masked
+//	--> device_fuse => false
+//	--> usb => [0483:df11]
+//	--> device_cgroup_rules => ["c 189:* rwm"]
+//	--> cgroupns => private
+//	--> cgroup_rw => false
+//	--> storage_size => 20G
+//	--> mounts => ["type=bind,source=/srv/data,target=/data,readonly"]
+//	--> pid => container:isolator-arch
+//	--> publish => [127.0.0.1:2222:22, 5353:5353/udp]
+//	--> device_read_iops => [/dev/nvme0n1:2000]
+//	--> device_write_iops => [/dev/nvme0n1:1000]
+//	--> cpu_shares => 0
+//	--> cpu_weight => 200
+//	--> memory_node_pin => "0"
+//	--> umask => "0027"
+//	--> labels => [team=platform]
+//	--> annotations => [io.kubernetes.pod.name=mypod]
+//	--> label_namespace => com.example
+//	--> ulimits => [core=unlimited]
+//	--> cgroup_conf => [cpu.idle=1]
+//	--> sysctls => [net.core.somaxconn=4096]
+//	--> proc_opts => "hidepid=2"
+//	--> network => "macvlan:eth0"
+//	--> ip => 192.168.1.50
+//	--> mac_address => auto
+//	--> oom_kill_disable => false
+//
+// Every container Isolator creates gets an entry, even with nothing but
+// defaults. Containers created before this file existed have none;
+// LoadContainerOptions reports them with ok false and the zero
+// RunOptions marked Unrecorded, which leaves out the defaults added since
+// they were created.
+func containersFile() string {
+	return ConfigPath("containers.hk")
+}
+
+func loadContainersDoc() *HkDocument {
+	path := containersFile()
+	if _, err := os.Stat(path); err == nil {
+		if doc, err := LoadHKFile(path); err == nil {
+			return doc
+		}
+	}
+	return NewHkDocument()
+}
+
+func runOptionsToHk(o RunOptions) *HkMap {
+	m := NewHkMap()
+	m.Set("timezone", hkStr(o.Timezone))
+	m.Set("locale", hkStr(o.Locale))
+	m.Set("runtime", hkStr(o.Runtime))
+	m.Set("no_passwd_entry", hkBoolV(o.NoPasswdEntry))
+	m.Set("passwd_file", hkStr(o.PasswdFile))
+	m.Set("group_file", hkStr(o.GroupFile))
+	m.Set("no_containerenv", hkBoolV(o.NoContainerEnv))
+	m.Set("no_hosts", hkBoolV(o.NoHosts))
+	m.Set("no_resolv_conf", hkBoolV(o.NoResolvConf))
+	m.Set("no_image_volumes", hkBoolV(o.NoImageVolumes))
+	m.Set("machine_id", hkStr(o.MachineID))
+	m.Set("seccomp_profile", hkStr(o.SeccompProfile))
+	m.Set("group_add", hkStr(o.GroupAdd))
+	m.Set("rootfs", hkStr(o.RootFS))
+	m.Set("cap_drop_all", hkBoolV(o.CapDropAll))
+	m.Set("cap_add", hkStrs(o.CapAdd))
+	m.Set("device_input", hkBoolV(o.DeviceInput))
+	m.Set("device_kvm", hkBoolV(o.DeviceKVM))
+	m.Set("device_video", hkBoolV(o.DeviceVideo))
+	m.Set("privileged_devices", hkBoolV(o.PrivilegedDevices))
+	m.Set("mask_paths", hkStrs(o.MaskPaths))
+	m.Set("no_mask_paths", hkBoolV(o.NoMaskPaths))
+	m.Set("sysfs", hkStr(o.SysFS))
+	m.Set("device_fuse", hkBoolV(o.DeviceFUSE))
+	m.Set("usb", hkStrs(o.USB))
+	m.Set("device_cgroup_rules", hkStrs(o.DeviceCgroupRules))
+	m.Set("cgroupns", hkStr(o.CgroupNS))
+	m.Set("cgroup_rw", hkBoolV(o.CgroupRW))
+	m.Set("storage_size", hkStr(o.StorageSize))
+	m.Set("mounts", hkStrs(o.Mounts))
+	m.Set("pid", hkStr(o.PID))
+	m.Set("publish", hkStrs(o.Publish))
+	m.Set("device_read_iops", hkStrs(o.DeviceReadIOPS))
+	m.Set("device_write_iops", hkStrs(o.DeviceWriteIOPS))
+	m.Set("cpu_shares", hkNum(float64(o.CPUShares)))
+	m.Set("cpu_weight", hkNum(float64(o.CPUWeight)))
+	m.Set("memory_node_pin", hkStr(o.MemoryNodes))
+	m.Set("umask", hkStr(o.Umask))
+	m.Set("labels", hkStrs(o.Labels))
+	m.Set("annotations", hkStrs(o.Annotations))
+	m.Set("label_namespace", hkStr(o.LabelNamespace))
+	m.Set("ulimits", hkStrs(o.Ulimits))
+	m.Set("cgroup_conf", hkStrs(o.CgroupConf))
+	m.Set("sysctls", hkStrs(o.Sysctls))
+	m.Set("proc_opts", hkStr(o.ProcOpts))
+	m.Set("network", hkStr(o.Network))
+	m.Set("ip", hkStr(o.IP))
+	m.Set("mac_address", hkStr(o.MACAddress))
+	m.Set("oom_kill_disable", hkBoolV(o.OOMKillDisable))
+	return m
+}
+
+func runOptionsFromHk(m *HkMap) RunOptions {
+	return RunOptions{
+		Timezone:          hkGetString(m, "timezone", ""),
+		Locale:            hkGetString(m, "locale", ""),
+		Runtime:           hkGetString(m, "runtime", ""),
+		NoPasswdEntry:     hkGetBool(m, "no_passwd_entry", false),
+		PasswdFile:        hkGetString(m, "passwd_file", ""),
+		GroupFile:         hkGetString(m, "group_file", ""),
+		NoContainerEnv:    hkGetBool(m, "no_containerenv", false),
+		NoHosts:           hkGetBool(m, "no_hosts", false),
+		NoResolvConf:      hkGetBool(m, "no_resolv_conf", false),
+		NoImageVolumes:    hkGetBool(m, "no_image_volumes", false),
+		MachineID:         hkGetString(m, "machine_id", ""),
+		SeccompProfile:    hkGetString(m, "seccomp_profile", ""),
+		GroupAdd:          hkGetString(m, "group_add", ""),
+		RootFS:            hkGetString(m, "rootfs", ""),
+		CapDropAll:        hkGetBool(m, "cap_drop_all", false),
+		CapAdd:            hkGetStrings(m, "cap_add"),
+		DeviceInput:       hkGetBool(m, "device_input", false),
+		DeviceKVM:         hkGetBool(m, "device_kvm", false),
+		DeviceVideo:       hkGetBool(m, "device_video", false),
+		PrivilegedDevices: hkGetBool(m, "privileged_devices", false),
+		MaskPaths:         hkGetStrings(m, "mask_paths"),
+		NoMaskPaths:       hkGetBool(m, "no_mask_paths", false),
+		SysFS:             hkGetString(m, "sysfs", ""),
+		DeviceFUSE:        hkGetBool(m, "device_fuse", false),
+		USB:               hkGetStrings(m, "usb"),
+		DeviceCgroupRules: hkGetStrings(m, "device_cgroup_rules"),
+		CgroupNS:          hkGetString(m, "cgroupns", ""),
+		CgroupRW:          hkGetBool(m, "cgroup_rw", false),
+		StorageSize:       hkGetString(m, "storage_size", ""),
+		Mounts:            hkGetStrings(m, "mounts"),
+		PID:               hkGetString(m, "pid", ""),
+		Publish:           hkGetStrings(m, "publish"),
+		DeviceReadIOPS:    hkGetStrings(m, "device_read_iops"),
+		DeviceWriteIOPS:   hkGetStrings(m, "device_write_iops"),
+		CPUShares:         int(hkGetNumber(m, "cpu_shares", 0)),
+		CPUWeight:         int(hkGetNumber(m, "cpu_weight", 0)),
+		MemoryNodes:       hkGetString(m, "memory_node_pin", ""),
+		Umask:             hkGetString(m, "umask", ""),
+		Labels:            hkGetStrings(m, "labels"),
+		Annotations:       hkGetStrings(m, "annotations"),
+		LabelNamespace:    hkGetString(m, "label_namespace", ""),
+		Ulimits:           hkGetStrings(m, "ulimits"),
+		CgroupConf:        hkGetStrings(m, "cgroup_conf"),
+		Sysctls:           hkGetStrings(m, "sysctls"),
+		ProcOpts:          hkGetString(m, "proc_opts", ""),
+		Network:           hkGetString(m, "network", ""),
+		IP:                hkGetString(m, "ip", ""),
+		MACAddress:        hkGetString(m, "mac_address", ""),
+		OOMKillDisable:    hkGetBool(m, "oom_kill_disable", false),
+	}
+}
+
+// LoadContainerOptions returns the options cont was created with, and
+// false if it has no record.
+func LoadContainerOptions(cont string) (RunOptions, bool) {
+	v, ok := loadContainersDoc().Section("containers").Get(cont)
+	if !ok || v.Kind != HkMapKind {
+		// An unrecorded container kept the image's own machine ID.
+		return RunOptions{MachineID: "none", Unrecorded: true}, false
+	}
+	return runOptionsFromHk(v.MapVal), true
+}
+
+// SaveContainerOptions records the options cont was just created with.
+func SaveContainerOptions(cont string, opts RunOptions) error {
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	doc := loadContainersDoc()
+	doc.Section("containers").Set(cont, HkValue{Kind: HkMapKind, MapVal: runOptionsToHk(opts)})
+	return WriteHKFile(containersFile(), doc)
+}
+
+// ForgetContainerOptions drops cont's record (and generated machine ID)
+// once the container is gone.
+func ForgetContainerOptions(cont string) {
+	forgetMachineID(cont)
+	doc := loadContainersDoc()
+	sec := doc.Section("containers")
+	if _, ok := sec.Get(cont); !ok {
+		return
+	}
+	sec.Delete(cont)
+	_ = WriteHKFile(containersFile(), doc)
+}
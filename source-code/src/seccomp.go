@@ -0,0 +1,74 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// seccompProfile is the part of Docker's seccomp profile format (which
+// podman and the OCI runtimes read) that validateSeccompProfile checks.
+// Podman turns the profile into the container's OCI spec when the
+// container is created, and the runtime compiles that into the BPF
+// filter, so a later edit of the file only affects containers created
+// after it.
+type seccompProfile struct {
+	DefaultAction string   `json:"defaultAction"`
+	Architectures []string `json:"architectures"`
+	Syscalls      []struct {
+		Name   string   `json:"name"`
+		Names  []string `json:"names"`
+		Action string   `json:"action"`
+	} `json:"syscalls"`
+}
+
+var seccompActions = []string{
+	"SCMP_ACT_ALLOW", "SCMP_ACT_ERRNO", "SCMP_ACT_KILL", "SCMP_ACT_KILL_PROCESS",
+	"SCMP_ACT_KILL_THREAD", "SCMP_ACT_TRAP", "SCMP_ACT_TRACE", "SCMP_ACT_LOG", "SCMP_ACT_NOTIFY",
+}
+
+// podmanSeccompProfile is where distro podman packages install their
+// default profile — the natural starting point for a custom one.
+const podmanSeccompProfile = "/usr/share/containers/seccomp.json"
+
+// validateSeccompProfile checks a --seccomp-profile: "unconfined", or an
+// absolute path to a readable profile in Docker's JSON format whose
+// actions are all ones libseccomp knows, so a typo fails the install
+// instead of container creation.
+func validateSeccompProfile(path string) error {
+	if path == "unconfined" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("--seccomp-profile %q should be an absolute path on the host, or 'unconfined'", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("--seccomp-profile: %v (podman's own profile, %s, is a good one to copy and edit)", err, podmanSeccompProfile)
+	}
+	var p seccompProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("--seccomp-profile %s isn't a JSON seccomp profile: %v", path, err)
+	}
+	if !stringInSlice(p.DefaultAction, seccompActions) {
+		return fmt.Errorf("--seccomp-profile %s: defaultAction %q should be one of %s", path, p.DefaultAction, strings.Join(seccompActions, ", "))
+	}
+	for i, sc := range p.Syscalls {
+		if sc.Name == "" && len(sc.Names) == 0 {
+			return fmt.Errorf("--seccomp-profile %s: syscalls[%d] names no syscall", path, i)
+		}
+		if !stringInSlice(sc.Action, seccompActions) {
+			return fmt.Errorf("--seccomp-profile %s: syscalls[%d] action %q should be one of %s", path, i, sc.Action, strings.Join(seccompActions, ", "))
+		}
+	}
+	return nil
+}
+
+func buildSeccompArgs(profile string) []string {
+	if profile == "" {
+		return nil
+	}
+	return []string{"--security-opt", "seccomp=" + profile}
+}
@@ -0,0 +1,71 @@
+package src
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAnonymousVolumes(t *testing.T) {
+	anon := strings.Repeat("ab12", 16)
+	out := anon + " /var/lib/postgresql/data\npgdata /srv/data\n\n"
+	got := parseAnonymousVolumes(out)
+	if want := []string{anon + ":/var/lib/postgresql/data"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAnonymousVolumes = %v, want %v (named volumes come back through the run options)", got, want)
+	}
+	if got := parseAnonymousVolumes(""); got != nil {
+		t.Errorf("parseAnonymousVolumes(\"\") = %v, want nil", got)
+	}
+}
+
+func TestWithVolumes(t *testing.T) {
+	args := []string{"--cgroup-manager", "cgroupfs", "run", "-d", "--name", "c", "img"}
+	got := withVolumes(args, []string{"v:/data"})
+	want := []string{"--cgroup-manager", "cgroupfs", "run", "--volume", "v:/data", "-d", "--name", "c", "img"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withVolumes = %v, want %v", got, want)
+	}
+	if args[3] != "-d" {
+		t.Errorf("withVolumes modified its input: %v", args)
+	}
+	if got := withVolumes(args, nil); !reflect.DeepEqual(got, args) {
+		t.Errorf("withVolumes(nil) = %v, want args unchanged", got)
+	}
+}
+
+func TestSnapshotTagSameSecond(t *testing.T) {
+	taken := map[string]bool{}
+	var tags []string
+	for i := 0; i < 3; i++ {
+		tag := snapshotTag("fedora", 1731000000, func(tag string) bool { return taken[tag] })
+		taken[tag] = true
+		tags = append(tags, tag)
+	}
+	want := []string{"isolator-snapshot/fedora:1731000000", "isolator-snapshot/fedora:1731000000-1", "isolator-snapshot/fedora:1731000000-2"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("snapshots within one second tagged %v, want %v", tags, want)
+	}
+}
+
+func TestSaveSnapshotsSameSecond(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1731000000, 0)
+	recs := []SnapshotRecord{
+		{Container: "fedora", Image: "isolator-snapshot/fedora:1731000000", CreatedAt: at},
+		{Container: "fedora", Image: "isolator-snapshot/fedora:1731000000-1", CreatedAt: at},
+	}
+	if err := saveSnapshots(recs); err != nil {
+		t.Fatal(err)
+	}
+	got := loadSnapshots()
+	if len(got) != 2 {
+		t.Fatalf("loadSnapshots kept %d of 2 same-second records: %v", len(got), got)
+	}
+	if latest := latestSnapshotFor("fedora", got); latest == nil || latest.Image != recs[1].Image {
+		t.Errorf("latestSnapshotFor = %v, want the later %s", latest, recs[1].Image)
+	}
+}
@@ -0,0 +1,70 @@
+package src
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+// storageSizeRe matches the sizes podman's --storage-opt size= accepts:
+// a number with an optional k/m/g/t unit (and an optional trailing b).
+var storageSizeRe = regexp.MustCompile(`^[0-9]+([kKmMgGtT][bB]?)?$`)
+
+// xfsSuperMagic is statfs(2)'s f_type for XFS.
+const xfsSuperMagic = 0x58465342
+
+func validateStorageSize(size string) error {
+	if !storageSizeRe.MatchString(size) || strings.Trim(size, "0kKmMgGtTbB") == "" {
+		return fmt.Errorf("%q is not a size (expected e.g. 20G or 512M)", size)
+	}
+	return nil
+}
+
+// storageQuotaSupport reports whether podman can cap a container's
+// writable layer, and why not if it can't. The overlay driver implements
+// size= with XFS project quotas on the upper directory, so the graph root
+// has to be overlay on XFS (mounted with pquota — podman itself checks
+// that part and fails the create with a clear error otherwise).
+func storageQuotaSupport() (bool, string) {
+	driver, root, err := podmanStore()
+	if err != nil {
+		return false, "couldn't ask podman for its storage driver"
+	}
+	if driver != "overlay" {
+		return false, "podman's storage driver is " + driver + ", not overlay"
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
+		return false, "couldn't stat " + root
+	}
+	if uint32(st.Type) != xfsSuperMagic {
+		return false, root + " isn't on XFS, which overlay size limits need"
+	}
+	return true, ""
+}
+
+// podmanStore returns podman's storage driver and graph root.
+func podmanStore() (driver, root string, err error) {
+	out, err := exec.Command(podmanBin, "info", "--format", "{{.Store.GraphDriverName}} {{.Store.GraphRoot}}").Output()
+	if err != nil {
+		return "", "", err
+	}
+	driver, root, _ = strings.Cut(strings.TrimSpace(string(out)), " ")
+	return driver, root, nil
+}
+
+// buildStorageArgs limits the container's writable (upper) layer to size,
+// on hosts that support it; elsewhere HostWarnings has already said the
+// limit is skipped, and the container is created without one rather than
+// failing outright.
+func buildStorageArgs(size string) []string {
+	if size == "" {
+		return nil
+	}
+	if ok, _ := storageQuotaSupport(); !ok {
+		return nil
+	}
+	return []string{"--storage-opt", "size=" + size}
+}
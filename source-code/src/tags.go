@@ -0,0 +1,170 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tagListAll is passed as podman's --limit so the registry's whole tag
+// list comes back: podman otherwise stops at 25, which would cut the list
+// before it's been sorted. Podman follows the tags/list pagination links
+// itself, with the same auth, registries.conf (insecure registries) and
+// proxy settings a pull uses.
+const tagListAll = "1000000"
+
+// remoteTag is one entry of `isolator tags`, also its --format json shape.
+type remoteTag struct {
+	Tag   string `json:"tag"`
+	Local bool   `json:"local"`
+}
+
+// listRemoteTags asks podman for every tag of repo.
+func listRemoteTags(repo string) ([]string, error) {
+	out, err := exec.Command(podmanBin, "search", "--list-tags", "--limit", tagListAll, "--format", "json", repo).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s failed: %s", repo, strings.TrimSpace(string(out)))
+	}
+	var results []struct {
+		Name string
+		Tags []string
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		return nil, fmt.Errorf("unexpected output from podman search: %v", err)
+	}
+	var tags []string
+	for _, r := range results {
+		tags = append(tags, r.Tags...)
+	}
+	return tags, nil
+}
+
+// localTags returns the tags of repo already in podman's image storage.
+func localTags(repo string) map[string]bool {
+	out, err := exec.Command(podmanBin, "images", "--format", "{{.Repository}}:{{.Tag}}").Output()
+	if err != nil {
+		return nil
+	}
+	tags := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if i := strings.LastIndex(line, ":"); i > 0 && imageRepoMatches(line[:i], repo) {
+			tags[line[i+1:]] = true
+		}
+	}
+	return tags
+}
+
+// imageRepoMatches compares a repository as podman lists it (always fully
+// qualified) with one as typed, which may leave out docker.io/ or its
+// library/ namespace.
+func imageRepoMatches(local, repo string) bool {
+	if local == repo {
+		return true
+	}
+	if imageRegistry(repo) == "" {
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+		repo = "docker.io/" + repo
+	}
+	return local == repo
+}
+
+// tagChunks splits a tag into alternating digit and non-digit runs, so
+// "1.10.2-alpine" compares as 1 . 10 . 2 -alpine rather than as a string.
+func tagChunks(tag string) []string {
+	var chunks []string
+	start := 0
+	for i := 1; i <= len(tag); i++ {
+		if i == len(tag) || unicode.IsDigit(rune(tag[i])) != unicode.IsDigit(rune(tag[i-1])) {
+			chunks = append(chunks, tag[start:i])
+			start = i
+		}
+	}
+	return chunks
+}
+
+// tagLess orders tags newest-version first: tags starting with a number
+// (optionally after "v") sort by their numeric parts, descending, and
+// come before names like "latest" or "edge", which sort alphabetically.
+// Between "1.2" and "1.2-rc1", the shorter (release) tag wins.
+func tagLess(a, b string) bool {
+	av, bv := isVersionTag(a), isVersionTag(b)
+	if av != bv {
+		return av
+	}
+	if !av {
+		return a < b
+	}
+	ac, bc := tagChunks(strings.TrimPrefix(a, "v")), tagChunks(strings.TrimPrefix(b, "v"))
+	for i := 0; i < len(ac) && i < len(bc); i++ {
+		an, aerr := strconv.ParseUint(ac[i], 10, 64)
+		bn, berr := strconv.ParseUint(bc[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			return an > bn
+		case aerr != nil || berr != nil:
+			if ac[i] != bc[i] {
+				return ac[i] < bc[i]
+			}
+		}
+	}
+	if len(ac) != len(bc) {
+		return len(ac) < len(bc)
+	}
+	return a < b
+}
+
+func isVersionTag(tag string) bool {
+	t := strings.TrimPrefix(tag, "v")
+	return t != "" && unicode.IsDigit(rune(t[0]))
+}
+
+// HandleTags lists repo's tags on its registry, newest version first,
+// marking the ones already pulled. limit > 0 keeps only that many.
+func HandleTags(repo string, limit int, format string) {
+	if format != "" && format != "json" {
+		PrintError(fmt.Sprintf("--format %q is not supported (expected json, or leave unset for a list)", format))
+		os.Exit(1)
+	}
+	if err := checkRegistryPolicy(LoadConfig(), repo+":latest"); err != nil {
+		PrintError(err.Error())
+		os.Exit(1)
+	}
+	names, err := listRemoteTags(repo)
+	if err != nil {
+		PrintError(err.Error())
+		os.Exit(1)
+	}
+	sort.Slice(names, func(i, j int) bool { return tagLess(names[i], names[j]) })
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	local := localTags(repo)
+	tags := make([]remoteTag, len(names))
+	for i, n := range names {
+		tags[i] = remoteTag{Tag: n, Local: local[n]}
+	}
+
+	if format == "json" {
+		out, _ := json.MarshalIndent(tags, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(tags) == 0 {
+		PrintInfo("No tags found for " + repo)
+		return
+	}
+	for _, t := range tags {
+		if t.Local {
+			fmt.Printf("  %s  %s\n", CyanStyle.Render(t.Tag), DimStyle.Render("(pulled)"))
+		} else {
+			fmt.Printf("  %s\n", t.Tag)
+		}
+	}
+}
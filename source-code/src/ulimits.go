@@ -0,0 +1,103 @@
+package src
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ulimitTypes are the resource names podman's --ulimit takes, as in
+// ulimit(1)/setrlimit(2) without the RLIMIT_ prefix.
+var ulimitTypes = []string{
+	"as", "core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue",
+	"nice", "nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+}
+
+// Without --ulimit a container inherits podman's own limits, which for
+// rootless podman means the host user's hard limits on open files, and
+// an unlimited core size wherever the host allows it — so one crash can
+// write a multi-GB core into the bind-mounted home. Unless a --ulimit
+// names them, new containers get:
+//
+//	core=0               no core dumps
+//	nofile=1024:524288   the soft/hard pair systemd and modern runtimes use,
+//	                     with the hard limit capped at podman's own
+//
+// nproc is deliberately left alone: with --userns=keep-id the container
+// runs as the host user's uid, and RLIMIT_NPROC counts every process of
+// that uid on the host, not just the container's.
+const (
+	defaultNofileSoft = 1024
+	defaultNofileHard = 524288
+)
+
+// validateUlimit checks a --ulimit entry: type=soft[:hard], each a
+// non-negative number or "unlimited" (-1 is accepted as podman's spelling).
+func validateUlimit(spec string) error {
+	name, val, ok := strings.Cut(spec, "=")
+	if !ok || !stringInSlice(name, ulimitTypes) {
+		return fmt.Errorf("--ulimit %q should be TYPE=SOFT[:HARD], with TYPE one of %s", spec, strings.Join(ulimitTypes, ", "))
+	}
+	soft, hard, hasHard := strings.Cut(val, ":")
+	s, err := parseUlimitValue(soft)
+	if err != nil {
+		return fmt.Errorf("--ulimit %q: %v", spec, err)
+	}
+	if hasHard {
+		h, err := parseUlimitValue(hard)
+		if err != nil {
+			return fmt.Errorf("--ulimit %q: %v", spec, err)
+		}
+		if h != -1 && (s == -1 || s > h) {
+			return fmt.Errorf("--ulimit %q: the soft limit can't be above the hard one", spec)
+		}
+	}
+	return nil
+}
+
+func parseUlimitValue(v string) (int64, error) {
+	if v == "unlimited" || v == "-1" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a number or 'unlimited'", v)
+	}
+	return n, nil
+}
+
+// effectiveUlimits is specs with the hardening defaults added for every
+// type they don't set.
+func effectiveUlimits(specs []string) []string {
+	set := map[string]bool{}
+	for _, s := range specs {
+		name, _, _ := strings.Cut(s, "=")
+		set[name] = true
+	}
+	out := append([]string(nil), specs...)
+	if !set["core"] {
+		out = append(out, "core=0")
+	}
+	if !set["nofile"] {
+		hard := uint64(defaultNofileHard)
+		var lim syscall.Rlimit
+		if syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim) == nil && lim.Max < hard {
+			hard = lim.Max
+		}
+		soft := uint64(defaultNofileSoft)
+		if soft > hard {
+			soft = hard
+		}
+		out = append(out, fmt.Sprintf("nofile=%d:%d", soft, hard))
+	}
+	return out
+}
+
+func buildUlimitArgs(specs []string) []string {
+	var args []string
+	for _, s := range effectiveUlimits(specs) {
+		args = append(args, "--ulimit", strings.ReplaceAll(s, "unlimited", "-1"))
+	}
+	return args
+}
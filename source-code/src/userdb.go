@@ -0,0 +1,76 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// validateUserDBFile checks that path is a readable host file in
+// passwd(5) format (fields == 7) or group(5) format (fields == 4), so a
+// wrong file fails the install instead of leaving a container where no
+// user resolves. Comments and blank lines are allowed, as nss_files
+// allows them.
+func validateUserDBFile(path string, fields int) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%q should be an absolute path on the host", path)
+	}
+	if strings.ContainsAny(path, ":,\n") {
+		return fmt.Errorf("%q contains ':', ',' or a newline, which podman can't pass through", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	idField := 2 // uid in passwd, gid in group
+	entries := 0
+	sc := bufio.NewScanner(f)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != fields {
+			return fmt.Errorf("%s:%d: expected %d ':'-separated fields, got %d", path, lineNo, fields, len(parts))
+		}
+		if parts[0] == "" {
+			return fmt.Errorf("%s:%d: empty name", path, lineNo)
+		}
+		if _, err := strconv.ParseUint(parts[idField], 10, 32); err != nil {
+			return fmt.Errorf("%s:%d: id %q is not a number", path, lineNo, parts[idField])
+		}
+		if fields == 7 {
+			if _, err := strconv.ParseUint(parts[3], 10, 32); err != nil {
+				return fmt.Errorf("%s:%d: gid %q is not a number", path, lineNo, parts[3])
+			}
+		}
+		entries++
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if entries == 0 {
+		return fmt.Errorf("%s has no entries", path)
+	}
+	return nil
+}
+
+// buildUserDBArgs bind-mounts the given host files read-only over the
+// container's /etc/passwd and /etc/group. A file given here is taken as
+// the complete database, so buildPasswdArgs doesn't also ask podman to
+// add the host user's entry to it.
+func buildUserDBArgs(passwdFile, groupFile string) []string {
+	var args []string
+	if passwdFile != "" {
+		args = append(args, "--volume", passwdFile+":/etc/passwd:ro")
+	}
+	if groupFile != "" {
+		args = append(args, "--volume", groupFile+":/etc/group:ro")
+	}
+	return args
+}